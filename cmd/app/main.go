@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net/netip"
 	"os"
+	"strings"
+	"github.com/box1bs/TelegraphicVault/pkg/blob"
 	"github.com/box1bs/TelegraphicVault/pkg/config"
 	"github.com/box1bs/TelegraphicVault/pkg/database"
 	"github.com/box1bs/TelegraphicVault/pkg/server"
 	"time"
+	_ "time/tzdata" // user timezones must resolve without system zoneinfo
 
 	"github.com/joho/godotenv"
 )
@@ -23,6 +28,32 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	blobs, err := blob.Open(context.Background(), config.BlobConfig{
+		Backend: os.Getenv("BLOB_BACKEND"),
+		Dir: envOr("BLOB_DIR", "data/blobs"),
+		Endpoint: os.Getenv("S3_ENDPOINT"),
+		Region: os.Getenv("S3_REGION"),
+		Bucket: os.Getenv("S3_BUCKET"),
+		AccessKey: os.Getenv("S3_ACCESS_KEY"),
+		SecretKey: os.Getenv("S3_SECRET_KEY"),
+		UseSSL: os.Getenv("S3_USE_SSL") == "true",
+	})
+	if err != nil {
+		log.Fatalf("Failed to open blob storage: %v", err)
+	}
+
+	var allowedNetworks []netip.Prefix
+	for _, network := range strings.Split(os.Getenv("FETCH_ALLOWED_NETWORKS"), ",") {
+		if network = strings.TrimSpace(network); network == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			log.Fatalf("Invalid FETCH_ALLOWED_NETWORKS: %v", err)
+		}
+		allowedNetworks = append(allowedNetworks, prefix)
+	}
+
 	panic(server.NewServer(
 		db,
 		&config.AuthConfig{
@@ -31,5 +62,43 @@ func main() {
 			AccessTokenTTL: 15 * time.Minute,
 			RefreshTokenTTL: 30 * 24 * time.Hour,
 		},
+		&config.FetchConfig{
+			Timeout: 10 * time.Second,
+			MaxBodySize: 2 << 20,
+			MaxRedirects: 5,
+			MaxConcurrent: 8,
+			UserAgent: "TelegraphicVault/1.0 (+bookmark metadata)",
+			AllowPrivateNetworks: os.Getenv("FETCH_ALLOW_PRIVATE_NETWORKS") == "true",
+			AllowedNetworks: allowedNetworks,
+		},
+		&config.ArchiveConfig{
+			MaxSnapshotSize: 20 << 20,
+			MaxAssets: 100,
+			UserQuota: 500 << 20,
+		},
+		&config.LinkCheckConfig{
+			Interval: 10 * time.Minute,
+			RecheckAfter: 7 * 24 * time.Hour,
+			BatchSize: 100,
+			FailureThreshold: 3,
+		},
+		&config.TrashConfig{
+			Retention: 30 * 24 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+		blobs,
+		&config.AttachmentConfig{
+			MaxFileSize: 25 << 20,
+			UserQuota: 1 << 30,
+			AllowedTypes: []string{"application/pdf", "image/png", "image/jpeg", "image/gif", "image/webp", "text/plain"},
+			SweepInterval: 10 * time.Minute,
+		},
 	).Run())
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
 }
\ No newline at end of file
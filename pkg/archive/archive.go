@@ -0,0 +1,320 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/box1bs/TelegraphicVault/pkg/config"
+	"github.com/box1bs/TelegraphicVault/pkg/fetch"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	ModeHTML = "html"
+	ModeText = "text"
+)
+
+var (
+	ErrUnsupportedMode    = errors.New("unsupported archive mode")
+	ErrUnsupportedContent = errors.New("page is not an html document")
+	ErrSnapshotTooLarge   = errors.New("snapshot exceeds the size limit")
+)
+
+var (
+	cssURLPattern   = regexp.MustCompile(`url\(\s*['"]?([^'")]+)['"]?\s*\)`)
+	styleEndPattern = regexp.MustCompile(`(?i)</style`)
+)
+
+// elements that are dropped from html snapshots, either because they execute code or embed remote content
+var strippedElements = map[string]bool{
+	"script": true, "noscript": true, "iframe": true, "frame": true, "frameset": true,
+	"object": true, "embed": true, "applet": true, "base": true,
+}
+
+type Snapshot struct {
+	Mode        string
+	ContentType string
+	Content     []byte
+}
+
+type Archiver struct {
+	fetcher *fetch.Fetcher
+	config  config.ArchiveConfig
+}
+
+func NewArchiver(fetcher *fetch.Fetcher, conf config.ArchiveConfig) *Archiver {
+	return &Archiver{fetcher: fetcher, config: conf}
+}
+
+// Snapshot downloads rawURL and returns either a self-contained html document with stylesheets
+// and images inlined and all scripts removed, or the article text of the page.
+func (a *Archiver) Snapshot(ctx context.Context, rawURL, mode string) (*Snapshot, error) {
+	if mode != ModeHTML && mode != ModeText {
+		return nil, ErrUnsupportedMode
+	}
+
+	resp, err := a.fetcher.Get(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fetch.IsHTML(resp) {
+		return nil, ErrUnsupportedContent
+	}
+
+	doc, err := fetch.ParseHTML(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := url.Parse(resp.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode == ModeText {
+		return &Snapshot{
+			Mode:        ModeText,
+			ContentType: "text/plain; charset=utf-8",
+			Content:     []byte(Article(doc)),
+		}, nil
+	}
+
+	inliner := &inliner{ctx: ctx, fetcher: a.fetcher, budget: a.config.MaxSnapshotSize, assets: a.config.MaxAssets}
+	inliner.rewrite(doc, base)
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, doc); err != nil {
+		return nil, err
+	}
+
+	if int64(buf.Len()) > a.config.MaxSnapshotSize {
+		return nil, ErrSnapshotTooLarge
+	}
+
+	return &Snapshot{Mode: ModeHTML, ContentType: "text/html; charset=utf-8", Content: buf.Bytes()}, nil
+}
+
+// inliner replaces references to stylesheets and images with their contents
+// until either the byte budget or the number of assets runs out.
+type inliner struct {
+	ctx     context.Context
+	fetcher *fetch.Fetcher
+	budget  int64
+	assets  int
+}
+
+func (in *inliner) rewrite(n *html.Node, base *url.URL) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode && (strippedElements[c.Data] || isPragma(c)) {
+			n.RemoveChild(c)
+		} else {
+			in.rewriteElement(c, base)
+			in.rewrite(c, base)
+		}
+		c = next
+	}
+}
+
+// isPragma matches <meta http-equiv>, which can redirect the archived page (refresh), set cookies
+// or override the content type it is served with.
+func isPragma(n *html.Node) bool {
+	return n.Data == "meta" && attr(n, "http-equiv") != ""
+}
+
+func (in *inliner) rewriteElement(n *html.Node, base *url.URL) {
+	if n.Type != html.ElementNode {
+		return
+	}
+
+	attrs := n.Attr[:0]
+	for _, a := range n.Attr {
+		key := strings.ToLower(a.Key)
+		if strings.HasPrefix(key, "on") || key == "srcset" || key == "integrity" {
+			continue
+		}
+		if (key == "href" || key == "src" || key == "action") && strings.HasPrefix(strings.ToLower(strings.TrimSpace(a.Val)), "javascript:") {
+			continue
+		}
+		if key == "style" {
+			a.Val = in.inlineCSSURLs(a.Val, base)
+		}
+		attrs = append(attrs, a)
+	}
+	n.Attr = attrs
+
+	switch n.Data {
+	case "link":
+		if !hasToken(attr(n, "rel"), "stylesheet") {
+			if hasToken(attr(n, "rel"), "preload") || hasToken(attr(n, "rel"), "prefetch") || hasToken(attr(n, "rel"), "modulepreload") {
+				n.Attr = nil
+			}
+			return
+		}
+		cssURL := resolve(base, attr(n, "href"))
+		css, ok := in.fetch(cssURL, "text/css")
+		if !ok {
+			return
+		}
+		cssBase, _ := url.Parse(cssURL)
+		n.Data = "style"
+		n.Attr = nil
+		n.AppendChild(&html.Node{Type: html.TextNode, Data: in.inlineCSSURLs(string(css.data), cssBase)})
+	case "style":
+		if n.FirstChild != nil && n.FirstChild.Type == html.TextNode {
+			n.FirstChild.Data = in.inlineCSSURLs(n.FirstChild.Data, base)
+		}
+	case "img", "input", "video", "audio", "source":
+		if src := attr(n, "src"); src != "" && !strings.HasPrefix(src, "data:") {
+			if dataURI, ok := in.dataURI(resolve(base, src)); ok {
+				setAttr(n, "src", dataURI)
+			}
+		}
+		if poster := attr(n, "poster"); poster != "" {
+			if dataURI, ok := in.dataURI(resolve(base, poster)); ok {
+				setAttr(n, "poster", dataURI)
+			}
+		}
+	case "a":
+		if raw := attr(n, "href"); raw != "" && !strings.HasPrefix(raw, "#") {
+			if href := resolve(base, raw); href != "" {
+				setAttr(n, "href", href)
+			}
+		}
+	}
+}
+
+func (in *inliner) inlineCSSURLs(css string, base *url.URL) string {
+	// style text is rendered verbatim, so it must not be able to close its element
+	css = styleEndPattern.ReplaceAllString(css, `<\/style`)
+
+	return cssURLPattern.ReplaceAllStringFunc(css, func(match string) string {
+		ref := cssURLPattern.FindStringSubmatch(match)[1]
+		if strings.HasPrefix(ref, "data:") || strings.HasPrefix(ref, "#") {
+			return match
+		}
+		if dataURI, ok := in.dataURI(resolve(base, ref)); ok {
+			return `url("` + dataURI + `")`
+		}
+		return match
+	})
+}
+
+func (in *inliner) dataURI(ref string) (string, bool) {
+	body, ok := in.fetch(ref, "")
+	if !ok {
+		return "", false
+	}
+	return body.dataURI(), true
+}
+
+type asset struct {
+	contentType string
+	data        []byte
+}
+
+func (a asset) dataURI() string {
+	return "data:" + a.contentType + ";base64," + base64.StdEncoding.EncodeToString(a.data)
+}
+
+func (in *inliner) fetch(ref, wantType string) (asset, bool) {
+	if ref == "" || in.assets <= 0 || in.budget <= 0 {
+		return asset{}, false
+	}
+	in.assets--
+
+	resp, err := in.fetcher.Get(in.ctx, ref)
+	if err != nil || resp.StatusCode != 200 || resp.Truncated {
+		return asset{}, false
+	}
+
+	if wantType != "" && resp.ContentType != wantType {
+		return asset{}, false
+	}
+
+	// base64 grows the payload by a third
+	cost := int64(len(resp.Body)) * 4 / 3
+	if cost > in.budget {
+		return asset{}, false
+	}
+	in.budget -= cost
+
+	contentType := resp.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return asset{contentType: contentType, data: resp.Body}, true
+}
+
+func resolve(base *url.URL, ref string) string {
+	u, err := base.Parse(strings.TrimSpace(ref))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func setAttr(n *html.Node, key, value string) {
+	for i, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			n.Attr[i].Val = value
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: value})
+}
+
+func hasToken(list, token string) bool {
+	for _, t := range strings.Fields(strings.ToLower(list)) {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
+
+func Compress(content []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(content); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
@@ -0,0 +1,201 @@
+package archive
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	minParagraphLength = 25
+	minArticleLength   = 200
+)
+
+var (
+	positiveHints = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|story|text|blog`)
+	negativeHints = regexp.MustCompile(`(?i)comment|meta|footer|footnote|sidebar|sponsor|share|social|related|promo|banner|advert|\bads?\b|widget|popup|cookie|subscribe|nav|menu|breadcrumb`)
+)
+
+// Article returns the main text of a page: paragraphs are scored by length and punctuation,
+// their scores are credited to the enclosing containers, and the best container together with
+// similarly scored siblings is rendered with Text. Pages without a clear candidate fall back to
+// the text of the whole body.
+func Article(doc *html.Node) string {
+	body := findElement(doc, "body")
+	if body == nil {
+		body = doc
+	}
+
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node
+
+	credit := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = classWeight(n)
+			candidates = append(candidates, n)
+		}
+		scores[n] += score
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type != html.ElementNode {
+			return
+		}
+		if skippedElements[n.Data] || classWeight(n) < 0 && !hasParagraphs(n) {
+			return
+		}
+
+		switch n.Data {
+		case "p", "pre", "td", "blockquote":
+			text := textContent(n)
+			if len(text) >= minParagraphLength {
+				score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+				credit(n.Parent, score)
+				if n.Parent != nil {
+					credit(n.Parent.Parent, score/2)
+				}
+			}
+			return
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(body)
+
+	var top *html.Node
+	var topScore float64
+	for _, n := range candidates {
+		score := scores[n] * (1 - linkDensity(n))
+		scores[n] = score
+		if top == nil || score > topScore {
+			top, topScore = n, score
+		}
+	}
+
+	if top == nil {
+		return Text(body)
+	}
+
+	parts := []string{}
+	threshold := max(10, topScore*0.2)
+	parent := top.Parent
+	if parent == nil {
+		parts = append(parts, Text(top))
+	} else {
+		for c := parent.FirstChild; c != nil; c = c.NextSibling {
+			if c == top {
+				parts = append(parts, Text(c))
+				continue
+			}
+			if score, ok := scores[c]; ok && score >= threshold {
+				parts = append(parts, Text(c))
+				continue
+			}
+			if c.Type == html.ElementNode && c.Data == "p" {
+				if text := textContent(c); len(text) > 80 && linkDensity(c) < 0.25 {
+					parts = append(parts, text)
+				}
+			}
+		}
+	}
+
+	article := strings.TrimSpace(strings.Join(parts, "\n"))
+	if len(article) < minArticleLength {
+		return Text(body)
+	}
+	return article
+}
+
+func classWeight(n *html.Node) float64 {
+	var weight float64
+	for _, hint := range []string{attr(n, "class"), attr(n, "id")} {
+		if hint == "" {
+			continue
+		}
+		if negativeHints.MatchString(hint) {
+			weight -= 25
+		}
+		if positiveHints.MatchString(hint) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// hasParagraphs keeps containers with misleading class names, such as "post-meta-wrapper",
+// from hiding the article they wrap.
+func hasParagraphs(n *html.Node) bool {
+	count := 0
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil && count < 3; c = c.NextSibling {
+			if c.Type == html.ElementNode && c.Data == "p" && len(textContent(c)) >= minParagraphLength {
+				count++
+			}
+			walk(c)
+		}
+	}
+	walk(n)
+	return count >= 3
+}
+
+// linkDensity is the share of the text of n that sits inside links.
+func linkDensity(n *html.Node) float64 {
+	total := len(textContent(n))
+	if total == 0 {
+		return 0
+	}
+
+	linked := 0
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			linked += len(textContent(n))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+
+	return float64(linked) / float64(total)
+}
+
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			sb.WriteByte(' ')
+		}
+		if n.Type == html.ElementNode && skippedElements[n.Data] {
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+func findElement(n *html.Node, tag string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == tag {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
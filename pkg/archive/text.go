@@ -0,0 +1,62 @@
+package archive
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// elements whose text is never part of the readable content of a page
+var skippedElements = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true, "template": true,
+	"svg": true, "canvas": true, "nav": true, "header": true, "footer": true,
+	"aside": true, "form": true, "button": true, "select": true, "iframe": true,
+}
+
+var blockElements = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "main": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "li": true, "dl": true, "dt": true, "dd": true,
+	"table": true, "tr": true, "blockquote": true, "pre": true, "figure": true,
+	"figcaption": true, "br": true, "hr": true,
+}
+
+// Text returns the visible text of n with one paragraph per line.
+func Text(n *html.Node) string {
+	var lines []string
+	var line strings.Builder
+
+	flush := func() {
+		if text := strings.Join(strings.Fields(line.String()), " "); text != "" {
+			lines = append(lines, text)
+		}
+		line.Reset()
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			line.WriteString(n.Data)
+			line.WriteByte(' ')
+			return
+		case html.ElementNode:
+			if skippedElements[n.Data] {
+				return
+			}
+			if blockElements[n.Data] {
+				flush()
+				defer flush()
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+
+	walk(n)
+	flush()
+
+	return strings.Join(lines, "\n")
+}
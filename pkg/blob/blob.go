@@ -0,0 +1,31 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/box1bs/TelegraphicVault/pkg/config"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps opaque content under string keys. Writing an existing key replaces its content.
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Open returns the store configured by conf. The S3 bucket is created when it does not exist.
+func Open(ctx context.Context, conf config.BlobConfig) (Store, error) {
+	switch conf.Backend {
+	case "", "local":
+		return NewLocal(conf.Dir)
+	case "s3":
+		return NewS3(ctx, conf)
+	default:
+		return nil, fmt.Errorf("unknown blob backend %q", conf.Backend)
+	}
+}
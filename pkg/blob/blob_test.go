@@ -0,0 +1,166 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/box1bs/TelegraphicVault/pkg/config"
+)
+
+// testStore runs the behavior every Store has to share against s.
+func testStore(t *testing.T, s Store) {
+	ctx := context.Background()
+	key := fmt.Sprintf("test%d", time.Now().UnixNano())
+
+	get := func(key string) string {
+		t.Helper()
+		r, err := s.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("get %s: %v", key, err)
+		}
+		defer r.Close()
+		content, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("read %s: %v", key, err)
+		}
+		return string(content)
+	}
+
+	if _, err := s.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("get before put: err = %v, want %v", err, ErrNotFound)
+	}
+
+	if err := s.Put(ctx, key, strings.NewReader("first"), 5, "text/plain"); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if got := get(key); got != "first" {
+		t.Fatalf("get = %q, want %q", got, "first")
+	}
+
+	if err := s.Put(ctx, key, strings.NewReader("second content"), 14, "text/plain"); err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+	if got := get(key); got != "second content" {
+		t.Fatalf("get after overwrite = %q, want %q", got, "second content")
+	}
+
+	large := bytes.Repeat([]byte("0123456789abcdef"), 1<<16)
+	if err := s.Put(ctx, key+"large", bytes.NewReader(large), int64(len(large)), "application/octet-stream"); err != nil {
+		t.Fatalf("put large: %v", err)
+	}
+	if got := get(key + "large"); got != string(large) {
+		t.Fatalf("large blob came back with %d bytes, want %d", len(got), len(large))
+	}
+
+	for _, k := range []string{key, key + "large"} {
+		if err := s.Delete(ctx, k); err != nil {
+			t.Fatalf("delete %s: %v", k, err)
+		}
+		if _, err := s.Get(ctx, k); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("get after delete: err = %v, want %v", err, ErrNotFound)
+		}
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatalf("deleting a missing blob: %v", err)
+	}
+}
+
+func TestLocal(t *testing.T) {
+	s, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+}
+
+func TestLocalRejectsInvalidKeys(t *testing.T) {
+	s, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"", "ab", "../etc/passwd", "ab/cd", `ab\cd`, "abc.tmp"} {
+		if err := s.Put(context.Background(), key, strings.NewReader("x"), 1, ""); err == nil {
+			t.Errorf("put %q succeeded", key)
+		}
+		if _, err := s.Get(context.Background(), key); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("get %q: err = %v, want an invalid key error", key, err)
+		}
+	}
+}
+
+func TestLocalPutKeepsOldContentOnShortWrite(t *testing.T) {
+	root := t.TempDir()
+	s, err := NewLocal(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "abcdef", strings.NewReader("complete"), 8, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(ctx, "abcdef", strings.NewReader("short"), 8, ""); err == nil {
+		t.Fatal("put with fewer bytes than its size succeeded")
+	}
+
+	r, err := s.Get(ctx, "abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if content, _ := io.ReadAll(r); string(content) != "complete" {
+		t.Fatalf("content = %q, want the previous content", content)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(root, "ab"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("%d files left in the fan-out directory, want only the blob", len(entries))
+	}
+}
+
+func TestOpenRejectsUnknownBackend(t *testing.T) {
+	if _, err := Open(context.Background(), config.BlobConfig{Backend: "ftp"}); err == nil {
+		t.Fatal("opened an unknown backend")
+	}
+}
+
+// TestS3 needs an S3-compatible server such as MinIO, e.g.
+//
+//	S3_TEST_ENDPOINT=localhost:9000 S3_TEST_ACCESS_KEY=minioadmin S3_TEST_SECRET_KEY=minioadmin go test ./pkg/blob
+func TestS3(t *testing.T) {
+	endpoint := os.Getenv("S3_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("S3_TEST_ENDPOINT is not set")
+	}
+
+	bucket := os.Getenv("S3_TEST_BUCKET")
+	if bucket == "" {
+		bucket = "telegraphicvault-test"
+	}
+
+	s, err := NewS3(context.Background(), config.BlobConfig{
+		Endpoint:  endpoint,
+		Region:    os.Getenv("S3_TEST_REGION"),
+		Bucket:    bucket,
+		AccessKey: os.Getenv("S3_TEST_ACCESS_KEY"),
+		SecretKey: os.Getenv("S3_TEST_SECRET_KEY"),
+		UseSSL:    os.Getenv("S3_TEST_USE_SSL") == "true",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+}
@@ -0,0 +1,68 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/box1bs/TelegraphicVault/pkg/config"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3 keeps blobs in a bucket of an S3-compatible service. Path-style addressing is used,
+// so MinIO and similar servers work without wildcard DNS.
+type S3 struct {
+	client *minio.Client
+	bucket string
+}
+
+func NewS3(ctx context.Context, conf config.BlobConfig) (*S3, error) {
+	if conf.Endpoint == "" || conf.Bucket == "" {
+		return nil, errors.New("s3 endpoint and bucket must be set")
+	}
+
+	client, err := minio.New(conf.Endpoint, &minio.Options{
+		Creds:        credentials.NewStaticV4(conf.AccessKey, conf.SecretKey, ""),
+		Secure:       conf.UseSSL,
+		Region:       conf.Region,
+		BucketLookup: minio.BucketLookupPath,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := client.BucketExists(ctx, conf.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, conf.Bucket, minio.MakeBucketOptions{Region: conf.Region}); err != nil {
+			return nil, err
+		}
+	}
+
+	return &S3{client: client, bucket: conf.Bucket}, nil
+}
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	// GetObject is lazy, a missing key only shows up on the first request
+	if _, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{}); err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
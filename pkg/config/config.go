@@ -1,6 +1,7 @@
 package config
 
 import (
+	"net/netip"
 	"time"
 )
 
@@ -9,4 +10,53 @@ type AuthConfig struct {
 	RefreshTokenSecret string
 	AccessTokenTTL     time.Duration
 	RefreshTokenTTL    time.Duration
-}
\ No newline at end of file
+}
+
+type FetchConfig struct {
+	Timeout              time.Duration
+	MaxBodySize          int64
+	MaxRedirects         int
+	MaxConcurrent        int
+	UserAgent            string
+	AllowPrivateNetworks bool
+	// AllowedNetworks are reachable even when they are not public, e.g. an intranet wiki
+	AllowedNetworks []netip.Prefix
+}
+
+type ArchiveConfig struct {
+	MaxSnapshotSize int64
+	MaxAssets       int
+	UserQuota       int64
+}
+
+type LinkCheckConfig struct {
+	Interval         time.Duration
+	RecheckAfter     time.Duration
+	BatchSize        int
+	FailureThreshold int
+}
+
+type TrashConfig struct {
+	Retention     time.Duration
+	PurgeInterval time.Duration
+}
+
+// BlobConfig selects where attachment content is kept: Backend "local" stores files under Dir,
+// "s3" uses a bucket on any S3-compatible service.
+type BlobConfig struct {
+	Backend   string
+	Dir       string
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+}
+
+type AttachmentConfig struct {
+	MaxFileSize   int64
+	UserQuota     int64
+	AllowedTypes  []string
+	SweepInterval time.Duration
+}
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TagAliasSet struct {
+	Tag 	string 		`json:"tag"`
+	Aliases []string 	`json:"aliases"`
+}
+
+// resolveAliases normalizes names and replaces every alias with the name of its canonical tag.
+func resolveAliases(tx *gorm.DB, userID uuid.UUID, names []string) ([]string, error) {
+	names = normalizeNames(names)
+	if len(names) == 0 {
+		return names, nil
+	}
+
+	var rows []struct {
+		Alias 	string
+		Name 	string
+	}
+	if err := tx.Table("tag_aliases").
+		Select("tag_aliases.alias, tags.name").
+		Joins("JOIN tags ON tags.id = tag_aliases.tag_id").
+		Where("tag_aliases.user_id = ? AND tag_aliases.alias IN ?", userID, names).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	canonical := make(map[string]string, len(rows))
+	for _, row := range rows {
+		canonical[row.Alias] = row.Name
+	}
+
+	resolved := make([]string, len(names))
+	for i, name := range names {
+		if tag, ok := canonical[name]; ok {
+			name = tag
+		}
+		resolved[i] = name
+	}
+
+	return resolved, nil
+}
+
+func (p *Postgres) resolveTagQuery(ctx context.Context, userID uuid.UUID, q TagQuery) (TagQuery, error) {
+	if userID == uuid.Nil || q.IsEmpty() {
+		return q, nil
+	}
+
+	tx := p.db.WithContext(ctx)
+	var err error
+	if q.All, err = resolveAliases(tx, userID, q.All); err != nil {
+		return q, err
+	}
+	if q.Any, err = resolveAliases(tx, userID, q.Any); err != nil {
+		return q, err
+	}
+	if q.None, err = resolveAliases(tx, userID, q.None); err != nil {
+		return q, err
+	}
+
+	return q, nil
+}
+
+func (p *Postgres) resolveTagName(ctx context.Context, userID uuid.UUID, name string) (string, error) {
+	if userID == uuid.Nil || name == "" {
+		return name, nil
+	}
+
+	resolved, err := resolveAliases(p.db.WithContext(ctx), userID, []string{name})
+	if err != nil {
+		return "", err
+	}
+
+	return resolved[0], nil
+}
+
+func (p *Postgres) ListTagAliases(ctx context.Context, userID uuid.UUID) ([]*TagAliasSet, error) {
+	var rows []struct {
+		Name 	string
+		Alias 	string
+	}
+	if err := p.db.WithContext(ctx).Table("tag_aliases").
+		Select("tags.name, tag_aliases.alias").
+		Joins("JOIN tags ON tags.id = tag_aliases.tag_id").
+		Where("tag_aliases.user_id = ?", userID).
+		Order("tags.name, tag_aliases.alias").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var sets []*TagAliasSet
+	for _, row := range rows {
+		if len(sets) == 0 || sets[len(sets)-1].Tag != row.Name {
+			sets = append(sets, &TagAliasSet{Tag: row.Name})
+		}
+		sets[len(sets)-1].Aliases = append(sets[len(sets)-1].Aliases, row.Alias)
+	}
+
+	return sets, nil
+}
+
+// SetTagAliases replaces the aliases of a tag, creating the tag if needed. An alias that is already
+// a tag of its own is merged into the canonical tag; an alias of another tag is moved over.
+func (p *Postgres) SetTagAliases(ctx context.Context, userID uuid.UUID, tagName string, aliases []string) (*TagAliasSet, error) {
+	set := &TagAliasSet{Aliases: []string{}}
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		resolved, err := resolveAliases(tx, userID, []string{tagName})
+		if err != nil {
+			return err
+		}
+
+		if resolved[0] == "" {
+			return model.ErrInvalidTag
+		}
+
+		tag, err := firstOrCreateTag(tx, userID, resolved[0])
+		if err != nil {
+			return err
+		}
+		set.Tag = tag.Name
+
+		for _, alias := range removeDuplicates(normalizeNames(aliases)) {
+			if alias != "" && alias != tag.Name {
+				set.Aliases = append(set.Aliases, alias)
+			}
+		}
+
+		if err := tx.Where("tag_id = ?", tag.ID).Delete(&model.TagAlias{}).Error; err != nil {
+			return err
+		}
+
+		if len(set.Aliases) > 0 {
+			if err := tx.Where("user_id = ? AND alias IN ?", userID, set.Aliases).Delete(&model.TagAlias{}).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, alias := range set.Aliases {
+			existing, err := p.getTag(tx, userID, alias)
+			if err == nil {
+				if err := mergeTagInto(tx, existing, tag); err != nil {
+					return err
+				}
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			if err := tx.Create(&model.TagAlias{Alias: alias, TagID: tag.ID, UserID: userID}).Error; err != nil {
+				return err
+			}
+		}
+
+		// merged tags bring their own aliases along
+		return tx.Model(&model.TagAlias{}).Where("tag_id = ?", tag.ID).Order("alias").Pluck("alias", &set.Aliases).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return set, nil
+}
+
+func (p *Postgres) DeleteTagAlias(ctx context.Context, userID uuid.UUID, alias string) error {
+	result := p.db.WithContext(ctx).Where("user_id = ? AND alias = ?", userID, normalizeName(alias)).Delete(&model.TagAlias{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
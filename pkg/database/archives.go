@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ArchiveUsage struct {
+	Count 		int64 	`json:"count"`
+	Size 		int64 	`json:"size"`
+	StoredSize 	int64 	`json:"stored_size"`
+	Quota 		int64 	`json:"quota"`
+}
+
+// SaveBookmarkArchive replaces the snapshot of a bookmark unless the user's archives would then
+// take more than quota bytes of storage.
+func (p *Postgres) SaveBookmarkArchive(ctx context.Context, archive *model.BookmarkArchive, quota int64) error {
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// serializes concurrent archiving by the same user so the quota check holds
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", archive.UserID.String()).Error; err != nil {
+			return err
+		}
+
+		var used int64
+		if err := tx.Model(&model.BookmarkArchive{}).
+			Where("user_id = ? AND bookmark_id <> ?", archive.UserID, archive.BookmarkID).
+			Select("COALESCE(SUM(stored_size), 0)").
+			Scan(&used).Error; err != nil {
+			return err
+		}
+
+		if quota > 0 && used + archive.StoredSize > quota {
+			return model.ErrQuotaExceeded
+		}
+
+		if err := tx.Where("bookmark_id = ?", archive.BookmarkID).Delete(&model.BookmarkArchive{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(archive).Error
+	})
+}
+
+func (p *Postgres) GetBookmarkArchive(ctx context.Context, userID, bookmarkID uuid.UUID) (*model.BookmarkArchive, error) {
+	var archive model.BookmarkArchive
+	if err := p.db.WithContext(ctx).Where("user_id = ? AND bookmark_id = ?", userID, bookmarkID).First(&archive).Error; err != nil {
+		return nil, err
+	}
+	return &archive, nil
+}
+
+func (p *Postgres) DeleteBookmarkArchive(ctx context.Context, userID, bookmarkID uuid.UUID) error {
+	result := p.db.WithContext(ctx).Where("user_id = ? AND bookmark_id = ?", userID, bookmarkID).Delete(&model.BookmarkArchive{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (p *Postgres) GetArchiveUsage(ctx context.Context, userID uuid.UUID) (*ArchiveUsage, error) {
+	var usage ArchiveUsage
+	if err := p.db.WithContext(ctx).Model(&model.BookmarkArchive{}).
+		Where("user_id = ?", userID).
+		Select("COUNT(*) AS count, COALESCE(SUM(size), 0) AS size, COALESCE(SUM(stored_size), 0) AS stored_size").
+		Scan(&usage).Error; err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
@@ -0,0 +1,218 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const blobSweepBatchSize = 100
+
+type AttachmentUsage struct {
+	Count 	int64 	`json:"count"`
+	Size 	int64 	`json:"size"`
+	Quota 	int64 	`json:"quota"`
+}
+
+// lockBlob serializes the transactions that add or remove the blob with the given digest.
+func lockBlob(tx *gorm.DB, sha256 string) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "blob:" + sha256).Error
+}
+
+// CreateAttachment adds a file to a note of the user unless the distinct content of the user's
+// attachments would then take more than quota bytes. upload is called only when no blob with the
+// same digest is stored yet, while the digest is locked, so identical files are kept once. It runs
+// after the rows are written; when the transaction still fails, remove deletes the uploaded content
+// unless a concurrent upload of the same file has stored it in the meantime.
+func (p *Postgres) CreateAttachment(ctx context.Context, attachment *model.Attachment, quota int64, upload, remove func() error) error {
+	uploaded := false
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id").Where("user_id = ? AND id = ?", attachment.UserID, attachment.NoteID).First(&model.Note{}).Error; err != nil {
+			return err
+		}
+
+		// serializes concurrent uploads by the same user so the quota check holds
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "attachments:" + attachment.UserID.String()).Error; err != nil {
+			return err
+		}
+		if err := lockBlob(tx, attachment.SHA256); err != nil {
+			return err
+		}
+
+		var held int64
+		if err := tx.Model(&model.Attachment{}).
+			Where("user_id = ? AND sha256 = ?", attachment.UserID, attachment.SHA256).
+			Count(&held).Error; err != nil {
+			return err
+		}
+
+		if quota > 0 && held == 0 {
+			usage, err := attachmentUsage(tx, attachment.UserID)
+			if err != nil {
+				return err
+			}
+			if usage.Size + attachment.Size > quota {
+				return model.ErrQuotaExceeded
+			}
+		}
+
+		var stored int64
+		if err := tx.Model(&model.Blob{}).Where("sha256 = ?", attachment.SHA256).Count(&stored).Error; err != nil {
+			return err
+		}
+		if stored == 0 {
+			if err := tx.Create(&model.Blob{
+				SHA256: attachment.SHA256,
+				Size: attachment.Size,
+				ContentType: attachment.ContentType,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Create(attachment).Error; err != nil {
+			return err
+		}
+
+		if stored == 0 {
+			uploaded = true
+			return upload()
+		}
+		return nil
+	})
+	if err == nil || !uploaded {
+		return err
+	}
+
+	// the blob row was rolled back, so nothing refers to the uploaded content
+	if cleanupErr := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockBlob(tx, attachment.SHA256); err != nil {
+			return err
+		}
+
+		var stored int64
+		if err := tx.Model(&model.Blob{}).Where("sha256 = ?", attachment.SHA256).Count(&stored).Error; err != nil {
+			return err
+		}
+		if stored > 0 {
+			return nil
+		}
+		return remove()
+	}); cleanupErr != nil {
+		return errors.Join(err, cleanupErr)
+	}
+
+	return err
+}
+
+// ListAttachments returns the attachments of a note, oldest first.
+func (p *Postgres) ListAttachments(ctx context.Context, userID, noteID uuid.UUID) ([]*model.Attachment, error) {
+	if _, err := p.GetNoteByID(ctx, userID, noteID); err != nil {
+		return nil, err
+	}
+
+	attachments := []*model.Attachment{}
+	if err := p.db.WithContext(ctx).
+		Where("user_id = ? AND note_id = ?", userID, noteID).
+		Order("created_at").
+		Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// GetAttachment returns an attachment of the user. Attachments of notes in the trash are not found.
+func (p *Postgres) GetAttachment(ctx context.Context, userID, id uuid.UUID) (*model.Attachment, error) {
+	var attachment model.Attachment
+	if err := p.db.WithContext(ctx).
+		Where("user_id = ? AND id = ? AND note_id IN (?)", userID, id,
+			p.db.Model(&model.Note{}).Select("id").Where("user_id = ?", userID)).
+		First(&attachment).Error; err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// DeleteAttachment removes an attachment; its blob is removed by PurgeOrphanBlobs once no attachment uses it.
+func (p *Postgres) DeleteAttachment(ctx context.Context, userID, id uuid.UUID) error {
+	result := p.db.WithContext(ctx).
+		Where("user_id = ? AND id = ? AND note_id IN (?)", userID, id,
+			p.db.Model(&model.Note{}).Select("id").Where("user_id = ?", userID)).
+		Delete(&model.Attachment{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetAttachmentUsage counts the attachments of the user and the bytes they take, identical files once.
+func (p *Postgres) GetAttachmentUsage(ctx context.Context, userID uuid.UUID) (*AttachmentUsage, error) {
+	return attachmentUsage(p.db.WithContext(ctx), userID)
+}
+
+func attachmentUsage(tx *gorm.DB, userID uuid.UUID) (*AttachmentUsage, error) {
+	var usage AttachmentUsage
+	if err := tx.Model(&model.Attachment{}).Where("user_id = ?", userID).Count(&usage.Count).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Model(&model.Blob{}).
+		Where("sha256 IN (?)", tx.Model(&model.Attachment{}).Select("sha256").Where("user_id = ?", userID)).
+		Select("COALESCE(SUM(size), 0)").
+		Scan(&usage.Size).Error; err != nil {
+		return nil, err
+	}
+
+	return &usage, nil
+}
+
+// PurgeOrphanBlobs deletes the blobs no attachment refers to. remove deletes the content from the
+// blob store and is called while the digest is locked, so a concurrent upload of the same file
+// either finds the blob gone or keeps it alive.
+func (p *Postgres) PurgeOrphanBlobs(ctx context.Context, remove func(sha256 string) error) (int, error) {
+	orphaned := "NOT EXISTS (SELECT 1 FROM attachments WHERE attachments.sha256 = blobs.sha256)"
+
+	var digests []string
+	if err := p.db.WithContext(ctx).Model(&model.Blob{}).
+		Where(orphaned).
+		Limit(blobSweepBatchSize).
+		Pluck("sha256", &digests).Error; err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, digest := range digests {
+		deleted := false
+		err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := lockBlob(tx, digest); err != nil {
+				return err
+			}
+
+			result := tx.Where("sha256 = ? AND " + orphaned, digest).Delete(&model.Blob{})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+
+			deleted = true
+			return remove(digest)
+		})
+		if err != nil {
+			return purged, err
+		}
+		if deleted {
+			purged++
+		}
+	}
+
+	return purged, nil
+}
@@ -2,33 +2,70 @@ package storage
 
 import (
 	"context"
+	"strings"
 	"github.com/box1bs/TelegraphicVault/pkg/model"
+	"github.com/box1bs/TelegraphicVault/pkg/rules"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// bookmarkDocument is the full-text document of a bookmark, matched by idx_bookmarks_document
+const bookmarkDocument = "to_tsvector('simple', coalesce(title, '') || ' ' || coalesce(description, '') || ' ' || coalesce(article_text, ''))"
+
 type BookmarkFilter struct {
 	UserID uuid.UUID 	`json:"user_id"`
 	Tag    string		`json:"tag"`
+	Tags   TagQuery		`json:"tags"`
+	Health string		`json:"health"`
+	Status string		`json:"status"`
+	Collection uuid.UUID `json:"collection"`
+	// Recursive includes the subcollections of Collection
+	Recursive bool 		`json:"recursive"`
+	Pinned *bool 		`json:"pinned"`
+	Favorite *bool 		`json:"favorite"`
 }
 
 func (p *Postgres) CreateBookmark(ctx context.Context, bookmark model.Bookmark) error {
-	return p.db.WithContext(ctx).Create(&bookmark).Error
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&bookmark).Error; err != nil {
+			return err
+		}
+		return applyTagRules(tx, &bookmark, bookmark.UserID, rules.BookmarkItem(&bookmark))
+	})
 }
 
 func (p *Postgres) getBookmark(ctx context.Context, userID uuid.UUID, uri string) (*model.Bookmark, error) {
 	var bookmark model.Bookmark
-	err := p.db.WithContext(ctx).Where("user_id = ? AND url = ?", userID, uri).First(&bookmark).Error
+	err := p.db.WithContext(ctx).Preload("Tags").Where("user_id = ? AND url = ?", userID, uri).First(&bookmark).Error
 	if err != nil {
 		return nil, err
 	}
 	return &bookmark, nil
 }
 
-func (p *Postgres) SearchBookmark(ctx context.Context, user_id uuid.UUID, query string) ([]model.Bookmark, error) {
+func (p *Postgres) GetBookmarkByID(ctx context.Context, userID, id uuid.UUID) (*model.Bookmark, error) {
+	var bookmark model.Bookmark
+	err := p.db.WithContext(ctx).Preload("Tags").Where("user_id = ? AND id = ?", userID, id).First(&bookmark).Error
+	if err != nil {
+		return nil, err
+	}
+	return &bookmark, nil
+}
+
+func (p *Postgres) SearchBookmark(ctx context.Context, filter BookmarkFilter, query string) ([]model.Bookmark, error) {
+	var err error
+	if filter.Tags, err = p.resolveTagQuery(ctx, filter.UserID, filter.Tags); err != nil {
+		return nil, err
+	}
+
 	var bookmarks []model.Bookmark
-	if err := p.db.WithContext(ctx).Where("user_id = ? AND title = ? OR user_id = ? AND url = ?", user_id, query, user_id, query).Find(&bookmarks).Error; err != nil {
+	q := p.db.WithContext(ctx).Model(&model.Bookmark{}).
+		Where("user_id = ?", filter.UserID).
+		Where("title = ? OR url = ? OR "+bookmarkDocument+" @@ phraseto_tsquery('simple', ?)", query, query, query)
+	q = p.applyTagQuery(q, filter.UserID, filter.Tags, "bookmarks", "bookmark_tags", "bookmark_id")
+
+	if err := q.Preload("Tags").Find(&bookmarks).Error; err != nil {
 		return nil, err
 	}
 
@@ -54,38 +91,73 @@ func (p *Postgres) UpdateBookmark(ctx context.Context, userID uuid.UUID, uri, ti
 		}
 	}
 
-	err = p.db.WithContext(ctx).Save(bookmark).Error
+	err = p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(bookmark).Error; err != nil {
+			return err
+		}
+		return applyTagRules(tx, bookmark, userID, rules.BookmarkItem(bookmark))
+	})
 	return bookmark, err
 }
 
+// DeleteBookmark moves a bookmark to the trash. Its tags and archive are kept for a restore.
 func (p *Postgres) DeleteBookmark(ctx context.Context, userID uuid.UUID, uri string) error {
-	query := p.db.WithContext(ctx).Where("user_id = ? AND url = ?", userID, uri)
-	var bookmark model.Bookmark
-	if err := query.First(&bookmark).Error; err != nil {
-		return err
-	}
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var bookmark model.Bookmark
+		if err := tx.Preload("Tags").Where("user_id = ? AND url = ?", userID, uri).First(&bookmark).Error; err != nil {
+			return err
+		}
 
-	if err := p.db.WithContext(ctx).
-		Model(&model.Tag{}).
-		Where("id IN ?", ExtractTagIDs(bookmark.Tags)).
-		UpdateColumn("count", gorm.Expr("GREATEST(count - ?, 0)", 1)).
-		Error; err != nil {
-		return err
-	}
+		result := tx.Delete(&bookmark)
+		if result.Error != nil {
+			return result.Error
+		}
 
-	result := query.Delete(&bookmark)
-	if result.Error != nil {
-		return result.Error
-	}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
 
-	if result.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
-	}
+		return recountTags(tx, ExtractTagIDs(bookmark.Tags))
+	})
+}
 
-	return nil
+// SaveBookmarkMetadata stores fetched page metadata. Title and description are only filled in when empty,
+// so values entered by the user are never overwritten.
+func (p *Postgres) SaveBookmarkMetadata(ctx context.Context, id uuid.UUID, title, description, canonicalURL, faviconURL, contentType string) error {
+	return p.db.WithContext(ctx).Model(&model.Bookmark{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]any{
+			"title": gorm.Expr("CASE WHEN title = '' THEN ? ELSE title END", title),
+			"description": gorm.Expr("CASE WHEN description = '' THEN ? ELSE description END", description),
+			"canonical_url": canonicalURL,
+			"favicon_url": faviconURL,
+			"content_type": contentType,
+			"fetched_at": gorm.Expr("CURRENT_TIMESTAMP"),
+		}).
+		Error
+}
+
+// SaveBookmarkArticle stores the readable text extracted from the bookmarked page.
+func (p *Postgres) SaveBookmarkArticle(ctx context.Context, id uuid.UUID, text string) error {
+	return p.db.WithContext(ctx).Model(&model.Bookmark{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]any{
+			"article_text": text,
+			"reading_minutes": readingMinutes(len(strings.Fields(text))),
+			"extracted_at": gorm.Expr("CURRENT_TIMESTAMP"),
+		}).
+		Error
 }
 
 func (p *Postgres) ListBookmarks(ctx context.Context, filter BookmarkFilter) ([]*model.Bookmark, error) {
+	var err error
+	if filter.Tag, err = p.resolveTagName(ctx, filter.UserID, filter.Tag); err != nil {
+		return nil, err
+	}
+	if filter.Tags, err = p.resolveTagQuery(ctx, filter.UserID, filter.Tags); err != nil {
+		return nil, err
+	}
+
 	var bookmarks []*model.Bookmark
 	query := p.db.WithContext(ctx).Model(&model.Bookmark{})
 
@@ -94,12 +166,36 @@ func (p *Postgres) ListBookmarks(ctx context.Context, filter BookmarkFilter) ([]
 	}
 
 	if filter.Tag != "" {
-	query = query.Joins("JOIN bookmark_tags ON bookmarks.id = bookmark_tags.bookmark_id").
-		Joins("JOIN tags ON tags.id = bookmark_tags.tag_id").
-		Where("tags.name = ?", filter.Tag)
+		condition, name, descendants := tagSubtreeCondition("tags.name", filter.Tag)
+		query = query.Where("bookmarks.id IN (?)", p.db.Table("bookmark_tags").
+			Select("bookmark_tags.bookmark_id").
+			Joins("JOIN tags ON tags.id = bookmark_tags.tag_id").
+			Where(condition, name, descendants))
+	}
+
+	query = p.applyTagQuery(query, filter.UserID, filter.Tags, "bookmarks", "bookmark_tags", "bookmark_id")
+
+	if filter.Health != "" {
+		query = query.Where("health = ?", filter.Health)
+	}
+
+	if filter.Status != "" {
+		query = query.Where("read_status = ?", filter.Status)
+	}
+
+	if filter.Collection != uuid.Nil {
+		query = collectionCondition(query, filter.UserID, filter.Collection, filter.Recursive)
+	}
+
+	if filter.Pinned != nil {
+		query = query.Where("pinned = ?", *filter.Pinned)
+	}
+
+	if filter.Favorite != nil {
+		query = query.Where("favorite = ?", *filter.Favorite)
 	}
 
-	err := query.Preload("Tags").Find(&bookmarks).Error
+	err = query.Preload("Tags").Order(pinnedOrder).Find(&bookmarks).Error
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,345 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// what happens to the contents of a deleted collection
+const (
+	CollectionCascade      = "cascade"
+	CollectionMoveToParent = "move"
+)
+
+// collectionSubtree selects the ids of a collection and all of its descendants.
+const collectionSubtree = `WITH RECURSIVE subtree AS (
+	SELECT id FROM collections WHERE id = ? AND user_id = ?
+	UNION ALL
+	SELECT collections.id FROM collections JOIN subtree ON collections.parent_id = subtree.id
+) SELECT id FROM subtree`
+
+type CollectionNode struct {
+	model.Collection
+	Notes     int64             `json:"notes"`
+	Bookmarks int64             `json:"bookmarks"`
+	Children  []*CollectionNode `json:"children"`
+}
+
+func (p *Postgres) getCollection(tx *gorm.DB, userID, id uuid.UUID) (*model.Collection, error) {
+	var collection model.Collection
+	if err := tx.Where("user_id = ? AND id = ?", userID, id).First(&collection).Error; err != nil {
+		return nil, err
+	}
+	return &collection, nil
+}
+
+func collectionSubtreeIDs(tx *gorm.DB, userID, id uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := tx.Raw(collectionSubtree, id, userID).Scan(&ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// CreateCollection adds a collection at the end of its parent's children.
+func (p *Postgres) CreateCollection(ctx context.Context, collection *model.Collection) error {
+	if collection.Name == "" {
+		return model.ErrInvalidCollection
+	}
+
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if collection.ParentID != nil {
+			if _, err := p.getCollection(tx, collection.UserID, *collection.ParentID); err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return model.ErrInvalidCollection
+				}
+				return err
+			}
+		}
+
+		var last *int
+		if err := siblings(tx, collection.UserID, collection.ParentID).Select("max(position)").Scan(&last).Error; err != nil {
+			return err
+		}
+		if last != nil {
+			collection.Position = *last + 1
+		}
+
+		return tx.Create(collection).Error
+	})
+}
+
+func siblings(tx *gorm.DB, userID uuid.UUID, parentID *uuid.UUID) *gorm.DB {
+	query := tx.Model(&model.Collection{}).Where("user_id = ?", userID)
+	if parentID == nil {
+		return query.Where("parent_id IS NULL")
+	}
+	return query.Where("parent_id = ?", *parentID)
+}
+
+// ListCollections returns the collection tree of the user with the number of notes
+// and bookmarks directly inside every collection.
+func (p *Postgres) ListCollections(ctx context.Context, userID uuid.UUID) ([]*CollectionNode, error) {
+	var collections []model.Collection
+	if err := p.db.WithContext(ctx).Where("user_id = ?", userID).Order("position, name").Find(&collections).Error; err != nil {
+		return nil, err
+	}
+
+	counts := func(items any) (map[uuid.UUID]int64, error) {
+		var rows []struct {
+			CollectionID uuid.UUID
+			Count        int64
+		}
+		if err := p.db.WithContext(ctx).Model(items).
+			Select("collection_id, count(*) AS count").
+			Where("user_id = ? AND collection_id IS NOT NULL", userID).
+			Group("collection_id").
+			Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		result := make(map[uuid.UUID]int64, len(rows))
+		for _, row := range rows {
+			result[row.CollectionID] = row.Count
+		}
+		return result, nil
+	}
+
+	noteCounts, err := counts(&model.Note{})
+	if err != nil {
+		return nil, err
+	}
+	bookmarkCounts, err := counts(&model.Bookmark{})
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[uuid.UUID]*CollectionNode, len(collections))
+	for _, collection := range collections {
+		nodes[collection.ID] = &CollectionNode{
+			Collection: collection,
+			Notes:      noteCounts[collection.ID],
+			Bookmarks:  bookmarkCounts[collection.ID],
+			Children:   []*CollectionNode{},
+		}
+	}
+
+	roots := []*CollectionNode{}
+	for _, collection := range collections {
+		node := nodes[collection.ID]
+		if parent, ok := nodes[derefID(collection.ParentID)]; ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	return roots, nil
+}
+
+func (p *Postgres) UpdateCollection(ctx context.Context, userID, id uuid.UUID, name, icon string) (*model.Collection, error) {
+	if name == "" {
+		return nil, model.ErrInvalidCollection
+	}
+
+	result := p.db.WithContext(ctx).Model(&model.Collection{}).
+		Where("user_id = ? AND id = ?", userID, id).
+		UpdateColumns(map[string]any{"name": name, "icon": icon})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return p.getCollection(p.db.WithContext(ctx), userID, id)
+}
+
+// MoveCollection moves a collection with its whole subtree under parentID, or to the top level when
+// parentID is nil, and places it at position among its new siblings.
+func (p *Postgres) MoveCollection(ctx context.Context, userID, id uuid.UUID, parentID *uuid.UUID, position int) (*model.Collection, error) {
+	var collection *model.Collection
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if collection, err = p.getCollection(tx, userID, id); err != nil {
+			return err
+		}
+
+		if parentID != nil {
+			subtree, err := collectionSubtreeIDs(tx, userID, id)
+			if err != nil {
+				return err
+			}
+			if slices.Contains(subtree, *parentID) {
+				return model.ErrInvalidCollection
+			}
+			if _, err := p.getCollection(tx, userID, *parentID); err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return model.ErrInvalidCollection
+				}
+				return err
+			}
+		}
+
+		var order []uuid.UUID
+		if err := siblings(tx, userID, parentID).Where("id <> ?", id).Order("position, name").Pluck("id", &order).Error; err != nil {
+			return err
+		}
+		position = min(max(position, 0), len(order))
+		order = slices.Insert(order, position, id)
+
+		if err := tx.Model(collection).UpdateColumn("parent_id", parentID).Error; err != nil {
+			return err
+		}
+		for i, sibling := range order {
+			if err := tx.Model(&model.Collection{}).Where("id = ?", sibling).UpdateColumn("position", i).Error; err != nil {
+				return err
+			}
+		}
+
+		collection.ParentID = parentID
+		collection.Position = position
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return collection, nil
+}
+
+// DeleteCollection removes a collection. With the cascade policy its subcollections are deleted too and
+// its notes and bookmarks are moved to the trash; otherwise they are moved to the parent of the deleted collection.
+func (p *Postgres) DeleteCollection(ctx context.Context, userID, id uuid.UUID, policy string) error {
+	if policy != CollectionCascade && policy != CollectionMoveToParent {
+		return model.ErrInvalidCollection
+	}
+
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		collection, err := p.getCollection(tx, userID, id)
+		if err != nil {
+			return err
+		}
+
+		if policy == CollectionMoveToParent {
+			var order, children []uuid.UUID
+			if err := siblings(tx, userID, collection.ParentID).Order("position, name").Pluck("id", &order).Error; err != nil {
+				return err
+			}
+			if err := siblings(tx, userID, &id).Order("position, name").Pluck("id", &children).Error; err != nil {
+				return err
+			}
+
+			// the subcollections take the place of the deleted collection among its siblings
+			at := slices.Index(order, id)
+			order = slices.Replace(order, at, at + 1, children...)
+
+			if err := tx.Model(&model.Collection{}).Where("parent_id = ?", id).UpdateColumn("parent_id", collection.ParentID).Error; err != nil {
+				return err
+			}
+			for i, sibling := range order {
+				if err := tx.Model(&model.Collection{}).Where("id = ?", sibling).UpdateColumn("position", i).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Model(&model.Note{}).Where("collection_id = ?", id).UpdateColumn("collection_id", collection.ParentID).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&model.Bookmark{}).Where("collection_id = ?", id).UpdateColumn("collection_id", collection.ParentID).Error; err != nil {
+				return err
+			}
+			return tx.Delete(collection).Error
+		}
+
+		ids, err := collectionSubtreeIDs(tx, userID, id)
+		if err != nil {
+			return err
+		}
+
+		var tagIDs []uuid.UUID
+		if err := tx.Raw(
+			"SELECT tag_id FROM note_tags WHERE note_id IN (?) UNION SELECT tag_id FROM bookmark_tags WHERE bookmark_id IN (?)",
+			tx.Model(&model.Note{}).Select("id").Where("collection_id IN ?", ids),
+			tx.Model(&model.Bookmark{}).Select("id").Where("collection_id IN ?", ids),
+		).Scan(&tagIDs).Error; err != nil {
+			return err
+		}
+
+		// notes and bookmarks go to the trash with their tags and archives
+		if err := tx.Where("collection_id IN ?", ids).Delete(&model.Note{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("collection_id IN ?", ids).Delete(&model.Bookmark{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id IN ?", ids).Delete(&model.Collection{}).Error; err != nil {
+			return err
+		}
+
+		return recountTags(tx, tagIDs)
+	})
+}
+
+// MoveToCollection puts notes and bookmarks into a collection, or takes them out of any collection
+// when collectionID is nil.
+func (p *Postgres) MoveToCollection(ctx context.Context, userID uuid.UUID, collectionID *uuid.UUID, noteIDs, bookmarkIDs []uuid.UUID) error {
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if collectionID != nil {
+			if _, err := p.getCollection(tx, userID, *collectionID); err != nil {
+				return err
+			}
+		}
+
+		for _, items := range []struct {
+			model any
+			ids   []uuid.UUID
+		}{{&model.Note{}, uniqueIDs(noteIDs)}, {&model.Bookmark{}, uniqueIDs(bookmarkIDs)}} {
+			if len(items.ids) == 0 {
+				continue
+			}
+			result := tx.Model(items.model).Where("user_id = ? AND id IN ?", userID, items.ids).UpdateColumn("collection_id", collectionID)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected != int64(len(items.ids)) {
+				return gorm.ErrRecordNotFound
+			}
+		}
+
+		return nil
+	})
+}
+
+// collectionCondition restricts a list to a collection, including its subcollections when recursive.
+func collectionCondition(query *gorm.DB, userID, collectionID uuid.UUID, recursive bool) *gorm.DB {
+	if !recursive {
+		return query.Where("collection_id = ?", collectionID)
+	}
+	return query.Where("collection_id IN ("+collectionSubtree+")", collectionID, userID)
+}
+
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+func derefID(id *uuid.UUID) uuid.UUID {
+	if id == nil {
+		return uuid.Nil
+	}
+	return *id
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// TagPair is an unordered pair of tags applied together to Weight notes and bookmarks.
+type TagPair struct {
+	Source 	uuid.UUID 	`json:"source"`
+	Target 	uuid.UUID 	`json:"target"`
+	Weight 	int64 		`json:"weight"`
+}
+
+type RelatedTag struct {
+	Name 	string 		`json:"name"`
+	Count 	int64 		`json:"count"`
+	Weight 	int64 		`json:"weight"`
+	Score 	float64 	`json:"score"`
+}
+
+func (p *Postgres) GetTagCooccurrences(ctx context.Context, userID uuid.UUID) ([]TagPair, error) {
+	var pairs []TagPair
+	err := p.db.WithContext(ctx).Raw(
+		"SELECT source, target, SUM(weight) AS weight FROM (" +
+		"SELECT a.tag_id AS source, b.tag_id AS target, COUNT(*) AS weight FROM note_tags a " +
+		"JOIN note_tags b ON b.note_id = a.note_id AND a.tag_id < b.tag_id " +
+		"JOIN notes ON notes.id = a.note_id AND notes.deleted_at IS NULL " +
+		"JOIN tags ON tags.id = a.tag_id WHERE tags.user_id = ? GROUP BY a.tag_id, b.tag_id " +
+		"UNION ALL " +
+		"SELECT a.tag_id AS source, b.tag_id AS target, COUNT(*) AS weight FROM bookmark_tags a " +
+		"JOIN bookmark_tags b ON b.bookmark_id = a.bookmark_id AND a.tag_id < b.tag_id " +
+		"JOIN bookmarks ON bookmarks.id = a.bookmark_id AND bookmarks.deleted_at IS NULL " +
+		"JOIN tags ON tags.id = a.tag_id WHERE tags.user_id = ? GROUP BY a.tag_id, b.tag_id" +
+		") pairs GROUP BY source, target ORDER BY weight DESC",
+		userID, userID,
+	).Scan(&pairs).Error
+	if err != nil {
+		return nil, err
+	}
+	return pairs, nil
+}
+
+// GetRelatedTags ranks the tags applied together with name by how many items share them.
+// Score is the Jaccard index of the two tags' item sets.
+func (p *Postgres) GetRelatedTags(ctx context.Context, userID uuid.UUID, name string, limit int) ([]*RelatedTag, error) {
+	name, err := p.resolveTagName(ctx, userID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := p.getTag(p.db.WithContext(ctx), userID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var related []*RelatedTag
+	err = p.db.WithContext(ctx).Raw(
+		"SELECT tags.name, tags.count, SUM(pairs.weight) AS weight FROM (" +
+		"SELECT b.tag_id, COUNT(*) AS weight FROM note_tags a " +
+		"JOIN note_tags b ON b.note_id = a.note_id AND b.tag_id <> a.tag_id " +
+		"JOIN notes ON notes.id = a.note_id AND notes.deleted_at IS NULL WHERE a.tag_id = ? GROUP BY b.tag_id " +
+		"UNION ALL " +
+		"SELECT b.tag_id, COUNT(*) AS weight FROM bookmark_tags a " +
+		"JOIN bookmark_tags b ON b.bookmark_id = a.bookmark_id AND b.tag_id <> a.tag_id " +
+		"JOIN bookmarks ON bookmarks.id = a.bookmark_id AND bookmarks.deleted_at IS NULL WHERE a.tag_id = ? GROUP BY b.tag_id" +
+		") pairs JOIN tags ON tags.id = pairs.tag_id GROUP BY tags.id, tags.name, tags.count " +
+		"ORDER BY weight DESC, tags.name LIMIT ?",
+		tag.ID, tag.ID, limit,
+	).Scan(&related).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range related {
+		if union := tag.Count + r.Count - r.Weight; union > 0 {
+			r.Score = float64(r.Weight) / float64(union)
+		}
+	}
+
+	return related, nil
+}
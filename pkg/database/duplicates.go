@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+	"github.com/box1bs/TelegraphicVault/pkg/urlnorm"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const normalizeBatchSize = 500
+
+type DuplicateGroup struct {
+	URL       string            `json:"url"`
+	Bookmarks []*model.Bookmark `json:"bookmarks"`
+}
+
+// backfillNormalizedURLs fills normalized_url of bookmarks created before it existed.
+func backfillNormalizedURLs(db *gorm.DB) error {
+	return normalizeURLs(db, db.Where("normalized_url IS NULL OR normalized_url = ''"))
+}
+
+// renormalizeQueryURLs refreshes normalized_url of URLs with several query parameters, whose
+// canonical form used to sort the values of repeated parameters. It runs once.
+func renormalizeQueryURLs(tx *gorm.DB) error {
+	return normalizeURLs(tx, tx.Where("url LIKE ?", "%?%&%"))
+}
+
+// normalizeURLs stores the canonical form of the URLs of the bookmarks matching condition
+// where it differs from normalized_url.
+func normalizeURLs(db *gorm.DB, condition *gorm.DB) error {
+	var bookmarks []*model.Bookmark
+	return db.Model(&model.Bookmark{}).Select("id, url, normalized_url").
+		Where(condition).
+		FindInBatches(&bookmarks, normalizeBatchSize, func(_ *gorm.DB, _ int) error {
+			for _, bookmark := range bookmarks {
+				normalized := urlnorm.Canonical(bookmark.URL)
+				if normalized == bookmark.NormalizedURL {
+					continue
+				}
+				if err := db.Model(&model.Bookmark{}).
+					Where("id = ?", bookmark.ID).
+					UpdateColumn("normalized_url", normalized).
+					Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// FindDuplicateBookmarks groups bookmarks that share a canonical URL, either of their own
+// address or of the canonical link declared by the page. Groups are ordered by URL and the
+// bookmarks inside them by creation time.
+func (p *Postgres) FindDuplicateBookmarks(ctx context.Context, userID uuid.UUID) ([]*DuplicateGroup, error) {
+	var bookmarks []*model.Bookmark
+	if err := p.db.WithContext(ctx).
+		Select("id, normalized_url, canonical_url, created_at").
+		Where("user_id = ?", userID).
+		Order("created_at").
+		Find(&bookmarks).Error; err != nil {
+		return nil, err
+	}
+
+	// union-find over bookmarks connected by a shared canonical URL
+	parent := make([]int, len(bookmarks))
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	owners := make(map[string]int)
+	for i, bookmark := range bookmarks {
+		parent[i] = i
+		keys := []string{bookmark.NormalizedURL}
+		if bookmark.CanonicalURL != "" {
+			keys = append(keys, urlnorm.Canonical(bookmark.CanonicalURL))
+		}
+		for _, key := range keys {
+			if key == "" {
+				continue
+			}
+			if owner, ok := owners[key]; ok {
+				parent[find(i)] = find(owner)
+			} else {
+				owners[key] = i
+			}
+		}
+	}
+
+	byRoot := make(map[int]*DuplicateGroup)
+	var groups []*DuplicateGroup
+	for i, bookmark := range bookmarks {
+		root := find(i)
+		group, ok := byRoot[root]
+		if !ok {
+			group = &DuplicateGroup{URL: bookmarks[root].NormalizedURL}
+			byRoot[root] = group
+			groups = append(groups, group)
+		}
+		group.Bookmarks = append(group.Bookmarks, bookmark)
+	}
+
+	duplicates := []*DuplicateGroup{}
+	for _, group := range groups {
+		if len(group.Bookmarks) > 1 {
+			duplicates = append(duplicates, group)
+		}
+	}
+	sort.Slice(duplicates, func(i, j int) bool { return duplicates[i].URL < duplicates[j].URL })
+
+	var ids []uuid.UUID
+	for _, group := range duplicates {
+		ids = append(ids, bookmarkIDs(group.Bookmarks)...)
+	}
+	if len(ids) == 0 {
+		return duplicates, nil
+	}
+
+	var loaded []*model.Bookmark
+	if err := p.db.WithContext(ctx).Preload("Tags").Where("id IN ?", ids).Find(&loaded).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uuid.UUID]*model.Bookmark, len(loaded))
+	for _, bookmark := range loaded {
+		byID[bookmark.ID] = bookmark
+	}
+	for _, group := range duplicates {
+		for i, bookmark := range group.Bookmarks {
+			group.Bookmarks[i] = byID[bookmark.ID]
+		}
+	}
+
+	return duplicates, nil
+}
+
+// MergeBookmarks folds the source bookmarks into the target: their tags are added to it, an empty title
+// or description is taken from them, and an archive is kept if the target has none. The sources are deleted.
+func (p *Postgres) MergeBookmarks(ctx context.Context, userID, targetID uuid.UUID, sourceIDs []uuid.UUID) (*model.Bookmark, error) {
+	var target model.Bookmark
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND id = ?", userID, targetID).First(&target).Error; err != nil {
+			return err
+		}
+
+		ids := make([]uuid.UUID, 0, len(sourceIDs))
+		seen := map[uuid.UUID]bool{targetID: true}
+		for _, id := range sourceIDs {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+
+		var sources []*model.Bookmark
+		if err := tx.Preload("Tags").Where("user_id = ? AND id IN ?", userID, ids).Order("created_at").Find(&sources).Error; err != nil {
+			return err
+		}
+		if len(sources) == 0 || len(sources) != len(ids) {
+			return gorm.ErrRecordNotFound
+		}
+
+		var tagIDs []uuid.UUID
+		for _, source := range sources {
+			tagIDs = append(tagIDs, ExtractTagIDs(source.Tags)...)
+			if target.Title == "" {
+				target.Title = source.Title
+			}
+			if target.Description == "" {
+				target.Description = source.Description
+			}
+		}
+
+		if err := tx.Exec(
+			"INSERT INTO bookmark_tags (bookmark_id, tag_id) SELECT DISTINCT ?::uuid, tag_id FROM bookmark_tags WHERE bookmark_id IN ? ON CONFLICT DO NOTHING",
+			targetID, ids,
+		).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&target).UpdateColumns(map[string]any{
+			"title": target.Title,
+			"description": target.Description,
+		}).Error; err != nil {
+			return err
+		}
+
+		var archived int64
+		if err := tx.Model(&model.BookmarkArchive{}).Where("bookmark_id = ?", targetID).Count(&archived).Error; err != nil {
+			return err
+		}
+		if archived == 0 {
+			var archive model.BookmarkArchive
+			err := tx.Where("bookmark_id IN ?", ids).Order("created_at DESC").First(&archive).Error
+			if err == nil {
+				if err := tx.Model(&archive).UpdateColumn("bookmark_id", targetID).Error; err != nil {
+					return err
+				}
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+
+		if err := tx.Where("bookmark_id IN ?", ids).Delete(&model.BookmarkArchive{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Exec("DELETE FROM bookmark_tags WHERE bookmark_id IN ?", ids).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Where("id IN ?", ids).Delete(&model.Bookmark{}).Error; err != nil {
+			return err
+		}
+
+		if err := recountTags(tx, tagIDs); err != nil {
+			return err
+		}
+
+		return tx.Preload("Tags").First(&target, "id = ?", targetID).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &target, nil
+}
+
+func bookmarkIDs(bookmarks []*model.Bookmark) []uuid.UUID {
+	ids := make([]uuid.UUID, len(bookmarks))
+	for i, bookmark := range bookmarks {
+		ids[i] = bookmark.ID
+	}
+	return ids
+}
@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+	"github.com/box1bs/TelegraphicVault/pkg/rules"
+	"github.com/box1bs/TelegraphicVault/pkg/urlnorm"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// what happens to an imported bookmark whose URL is already saved
+const (
+	ImportSkip      = "skip"
+	ImportOverwrite = "overwrite"
+	ImportMerge     = "merge"
+)
+
+type ImportOptions struct {
+	Policy string
+	// DryRun only reports what the import would do
+	DryRun bool
+}
+
+// ImportItem is a row of a dry-run preview with the action the import would take.
+type ImportItem struct {
+	URL        string     `json:"url"`
+	Title      string     `json:"title"`
+	Tags       []string   `json:"tags"`
+	ReadAt     *time.Time `json:"read_at,omitempty"`
+	ReadStatus string     `json:"read_status,omitempty"`
+	Action     string     `json:"action"`
+}
+
+type ImportFailure struct {
+	URL   string `json:"url"`
+	Error string `json:"error"`
+}
+
+type ImportReport struct {
+	Created int             `json:"created"`
+	Updated int             `json:"updated"`
+	Skipped int             `json:"skipped"`
+	Failed  []ImportFailure `json:"failed"`
+	Preview []ImportItem    `json:"preview,omitempty"`
+}
+
+// ImportBookmarks saves bookmarks read from an export file. Their Tags only need names. Each bookmark
+// is imported on its own, so a failing row is reported without rolling back the others.
+func (p *Postgres) ImportBookmarks(ctx context.Context, userID uuid.UUID, bookmarks []*model.Bookmark, opts ImportOptions) (*ImportReport, error) {
+	if opts.Policy != ImportSkip && opts.Policy != ImportOverwrite && opts.Policy != ImportMerge {
+		return nil, model.ErrInvalidImport
+	}
+
+	if opts.DryRun {
+		return p.previewImport(ctx, userID, bookmarks, opts.Policy)
+	}
+
+	report := &ImportReport{Failed: []ImportFailure{}}
+	for _, bookmark := range bookmarks {
+		result, err := p.importBookmark(ctx, userID, bookmark, opts.Policy)
+		if err != nil {
+			if ctx.Err() != nil {
+				return report, ctx.Err()
+			}
+			report.Failed = append(report.Failed, ImportFailure{URL: bookmark.URL, Error: err.Error()})
+			continue
+		}
+		report.count(result)
+	}
+
+	return report, nil
+}
+
+// previewImport decides the action for every bookmark the same way the import does,
+// including duplicates inside the file itself, without writing anything.
+func (p *Postgres) previewImport(ctx context.Context, userID uuid.UUID, bookmarks []*model.Bookmark, policy string) (*ImportReport, error) {
+	report := &ImportReport{Failed: []ImportFailure{}, Preview: []ImportItem{}}
+
+	seen := make(map[string]bool)
+	for _, bookmark := range bookmarks {
+		normalized := urlnorm.Canonical(bookmark.URL)
+
+		exists := seen[normalized]
+		if !exists {
+			var count int64
+			if err := p.db.WithContext(ctx).Model(&model.Bookmark{}).
+				Where("user_id = ? AND (url = ? OR normalized_url = ?)", userID, bookmark.URL, normalized).
+				Count(&count).Error; err != nil {
+				return nil, err
+			}
+			exists = count > 0
+		}
+		seen[normalized] = true
+
+		result := importCreated
+		if exists {
+			result = importUpdated
+			if policy == ImportSkip {
+				result = importSkipped
+			}
+		}
+		report.count(result)
+
+		item := ImportItem{URL: bookmark.URL, Title: bookmark.Title, Tags: []string{}, ReadAt: bookmark.ReadAt, ReadStatus: bookmark.ReadStatus, Action: result.String()}
+		for _, tag := range bookmark.Tags {
+			item.Tags = append(item.Tags, tag.Name)
+		}
+		report.Preview = append(report.Preview, item)
+	}
+
+	return report, nil
+}
+
+func (r *ImportReport) count(result importResult) {
+	switch result {
+	case importCreated:
+		r.Created++
+	case importUpdated:
+		r.Updated++
+	default:
+		r.Skipped++
+	}
+}
+
+type importResult int
+
+const (
+	importSkipped importResult = iota
+	importCreated
+	importUpdated
+)
+
+func (r importResult) String() string {
+	switch r {
+	case importCreated:
+		return "create"
+	case importUpdated:
+		return "update"
+	default:
+		return "skip"
+	}
+}
+
+func (p *Postgres) importBookmark(ctx context.Context, userID uuid.UUID, bookmark *model.Bookmark, policy string) (importResult, error) {
+	tagNames := make([]string, len(bookmark.Tags))
+	for i, tag := range bookmark.Tags {
+		tagNames[i] = tag.Name
+	}
+
+	result := importSkipped
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing model.Bookmark
+		err := tx.Preload("Tags").
+			Where("user_id = ? AND (url = ? OR normalized_url = ?)", userID, bookmark.URL, urlnorm.Canonical(bookmark.URL)).
+			First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			created := model.Bookmark{
+				ID: uuid.New(),
+				URL: bookmark.URL,
+				Title: bookmark.Title,
+				Description: bookmark.Description,
+				UserID: userID,
+				CreatedAt: bookmark.CreatedAt,
+				ReadAt: bookmark.ReadAt,
+			}
+			if importedAsRead(bookmark) {
+				created.ReadStatus = model.ReadStatusRead
+			}
+			if err := tx.Create(&created).Error; err != nil {
+				return err
+			}
+			if err := appendTags(tx, &created, userID, tagNames); err != nil {
+				return err
+			}
+			result = importCreated
+			return applyTagRules(tx, &created, userID, rules.BookmarkItem(&created))
+		}
+		if err != nil {
+			return err
+		}
+
+		switch policy {
+		case ImportOverwrite:
+			existing.Title = bookmark.Title
+			existing.Description = bookmark.Description
+			if importedAsRead(bookmark) {
+				if bookmark.ReadAt != nil {
+					existing.ReadAt = bookmark.ReadAt
+				}
+				existing.ReadStatus = model.ReadStatusRead
+			}
+			if err := replaceBookmarkTags(tx, &existing, tagNames); err != nil {
+				return err
+			}
+		case ImportMerge:
+			if existing.Title == "" {
+				existing.Title = bookmark.Title
+			}
+			if existing.Description == "" {
+				existing.Description = bookmark.Description
+			}
+			if existing.ReadStatus != model.ReadStatusRead && importedAsRead(bookmark) {
+				existing.ReadAt = bookmark.ReadAt
+				existing.ReadStatus = model.ReadStatusRead
+			}
+			if err := appendTags(tx, &existing, userID, tagNames); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+
+		if err := tx.Model(&existing).UpdateColumns(map[string]any{
+			"title": existing.Title,
+			"description": existing.Description,
+			"read_at": existing.ReadAt,
+			"read_status": existing.ReadStatus,
+		}).Error; err != nil {
+			return err
+		}
+		result = importUpdated
+		return applyTagRules(tx, &existing, userID, rules.BookmarkItem(&existing))
+	})
+
+	return result, err
+}
+
+// importedAsRead reports whether an imported bookmark was marked as read, with or without the time it was read.
+func importedAsRead(bookmark *model.Bookmark) bool {
+	return bookmark.ReadStatus == model.ReadStatusRead || bookmark.ReadAt != nil
+}
+
+// replaceBookmarkTags sets the tags of a bookmark loaded with its Tags to exactly tagNames.
+func replaceBookmarkTags(tx *gorm.DB, bookmark *model.Bookmark, tagNames []string) error {
+	removed := ExtractTagIDs(bookmark.Tags)
+	if err := tx.Model(bookmark).Association("Tags").Clear(); err != nil {
+		return err
+	}
+	if err := recountTags(tx, removed); err != nil {
+		return err
+	}
+	return appendTags(tx, bookmark, bookmark.UserID, tagNames)
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	"github.com/google/uuid"
+)
+
+// JournalDay is a day that has a journal note.
+type JournalDay struct {
+	Date 	string 		`json:"date"`
+	NoteID 	uuid.UUID 	`json:"note_id"`
+	Title 	string 		`json:"title"`
+}
+
+// GetJournalNote returns the journal note of the user for date, given as YYYY-MM-DD. The
+// idx_notes_user_journal_date index keeps it unique.
+func (p *Postgres) GetJournalNote(ctx context.Context, userID uuid.UUID, date string) (*model.Note, error) {
+	var note model.Note
+	if err := p.db.WithContext(ctx).Preload("Tags").
+		Where("user_id = ? AND type = ? AND journal_date = ?", userID, model.NoteTypeJournal, date).
+		Take(&note).Error; err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// ListJournalDays returns the days from from to to, both inclusive, that have a journal note,
+// in date order. An empty bound leaves that side open.
+func (p *Postgres) ListJournalDays(ctx context.Context, userID uuid.UUID, from, to string) ([]*JournalDay, error) {
+	query := p.db.WithContext(ctx).Model(&model.Note{}).
+		Select("journal_date AS date, id AS note_id, title").
+		Where("user_id = ? AND type = ? AND journal_date IS NOT NULL", userID, model.NoteTypeJournal)
+
+	if from != "" {
+		query = query.Where("journal_date >= ?", from)
+	}
+	if to != "" {
+		query = query.Where("journal_date <= ?", to)
+	}
+
+	days := []*JournalDay{}
+	if err := query.Order("journal_date").Scan(&days).Error; err != nil {
+		return nil, err
+	}
+
+	return days, nil
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+	"github.com/box1bs/TelegraphicVault/pkg/urlnorm"
+
+	"github.com/google/uuid"
+)
+
+var noteURL = regexp.MustCompile(`https?://[^\s<>()\[\]{}"'` + "`" + `]+`)
+
+type GraphFilter struct {
+	UserID uuid.UUID `json:"user_id"`
+	// Tag and Collection include their descendants
+	Tag        string    `json:"tag"`
+	Collection uuid.UUID `json:"collection"`
+}
+
+// NoteReference is a bookmarked URL mentioned in the content of a note.
+type NoteReference struct {
+	NoteID     uuid.UUID `json:"note_id"`
+	BookmarkID uuid.UUID `json:"bookmark_id"`
+}
+
+// KnowledgeGraph holds the notes and bookmarks in scope with their tags, the links between the notes
+// and the bookmarks the notes mention.
+type KnowledgeGraph struct {
+	Notes      []*model.Note
+	Bookmarks  []*model.Bookmark
+	Links      []*model.NoteLink
+	References []NoteReference
+}
+
+func (p *Postgres) GetKnowledgeGraph(ctx context.Context, filter GraphFilter) (*KnowledgeGraph, error) {
+	notes, err := p.ListNotes(ctx, NoteFilter{UserID: filter.UserID, Tag: filter.Tag, Collection: filter.Collection, Recursive: true})
+	if err != nil {
+		return nil, err
+	}
+
+	bookmarks, err := p.ListBookmarks(ctx, BookmarkFilter{UserID: filter.UserID, Tag: filter.Tag, Collection: filter.Collection, Recursive: true})
+	if err != nil {
+		return nil, err
+	}
+
+	kg := &KnowledgeGraph{Notes: notes, Bookmarks: bookmarks, Links: []*model.NoteLink{}, References: []NoteReference{}}
+	if len(notes) == 0 {
+		return kg, nil
+	}
+
+	noteIDs := make([]uuid.UUID, 0, len(notes))
+	for _, note := range notes {
+		noteIDs = append(noteIDs, note.ID)
+	}
+
+	if err := p.db.WithContext(ctx).
+		Where("source_id IN ? AND target_id IN ?", noteIDs, noteIDs).
+		Find(&kg.Links).Error; err != nil {
+		return nil, err
+	}
+
+	byURL := make(map[string]uuid.UUID, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		byURL[bookmark.NormalizedURL] = bookmark.ID
+	}
+
+	for _, note := range notes {
+		seen := make(map[uuid.UUID]bool)
+		for _, raw := range noteURL.FindAllString(note.Content, -1) {
+			id, ok := byURL[urlnorm.Canonical(strings.TrimRight(raw, ".,;:!?"))]
+			if ok && !seen[id] {
+				seen[id] = true
+				kg.References = append(kg.References, NoteReference{NoteID: note.ID, BookmarkID: id})
+			}
+		}
+	}
+
+	return kg, nil
+}
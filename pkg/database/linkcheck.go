@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+	"github.com/box1bs/TelegraphicVault/pkg/urlnorm"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LinkCheck is the outcome of re-checking a bookmark URL.
+type LinkCheck struct {
+	StatusCode  int
+	RedirectURL string
+	Failed      bool
+}
+
+// ListBookmarksToCheck returns the bookmarks of all users that were never checked or were last checked
+// before checkedBefore, least recently checked first. Only id and url are loaded.
+func (p *Postgres) ListBookmarksToCheck(ctx context.Context, checkedBefore time.Time, limit int) ([]*model.Bookmark, error) {
+	var bookmarks []*model.Bookmark
+	err := p.db.WithContext(ctx).Model(&model.Bookmark{}).
+		Select("id, url").
+		Where("checked_at IS NULL OR checked_at < ?", checkedBefore).
+		Order("checked_at NULLS FIRST").
+		Limit(limit).
+		Find(&bookmarks).Error
+	if err != nil {
+		return nil, err
+	}
+	return bookmarks, nil
+}
+
+// SaveLinkCheck records a check result. A bookmark becomes broken after failureThreshold consecutive
+// failures; a single success resets the counter.
+func (p *Postgres) SaveLinkCheck(ctx context.Context, id uuid.UUID, check LinkCheck, failureThreshold int) error {
+	columns := map[string]any{
+		"status_code": check.StatusCode,
+		"checked_at": gorm.Expr("CURRENT_TIMESTAMP"),
+	}
+
+	if check.Failed {
+		columns["link_failures"] = gorm.Expr("link_failures + 1")
+		columns["health"] = gorm.Expr("CASE WHEN link_failures + 1 >= ? THEN ? ELSE health END", failureThreshold, model.LinkHealthBroken)
+	} else {
+		columns["link_failures"] = 0
+		columns["redirect_url"] = check.RedirectURL
+		columns["health"] = model.LinkHealthOK
+		if check.RedirectURL != "" {
+			columns["health"] = model.LinkHealthRedirected
+		}
+	}
+
+	return p.db.WithContext(ctx).Model(&model.Bookmark{}).Where("id = ?", id).UpdateColumns(columns).Error
+}
+
+// ApplyBookmarkRedirect replaces the url of a redirected bookmark with its redirect target.
+func (p *Postgres) ApplyBookmarkRedirect(ctx context.Context, userID, id uuid.UUID) (*model.Bookmark, error) {
+	var bookmark model.Bookmark
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND id = ?", userID, id).First(&bookmark).Error; err != nil {
+			return err
+		}
+
+		if bookmark.RedirectURL == "" {
+			return model.ErrNoRedirect
+		}
+
+		normalized := urlnorm.Canonical(bookmark.RedirectURL)
+
+		var count int64
+		if err := tx.Model(&model.Bookmark{}).
+			Where("user_id = ? AND (url = ? OR normalized_url = ?) AND id <> ?", userID, bookmark.RedirectURL, normalized, id).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return model.ErrAlreadyExists
+		}
+
+		if err := tx.Model(&bookmark).UpdateColumns(map[string]any{
+			"url": bookmark.RedirectURL,
+			"normalized_url": normalized,
+			"redirect_url": "",
+			"health": model.LinkHealthOK,
+			"link_failures": 0,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Preload("Tags").First(&bookmark, "id = ?", id).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &bookmark, nil
+}
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+	"github.com/box1bs/TelegraphicVault/pkg/wikilink"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const linkBatchSize = 500
+
+// backfillNoteLinks indexes the links of notes written before the link table existed.
+func backfillNoteLinks(db *gorm.DB) error {
+	var indexed int64
+	if err := db.Model(&model.NoteLink{}).Count(&indexed).Error; err != nil {
+		return err
+	}
+	if indexed > 0 {
+		return nil
+	}
+
+	var notes []*model.Note
+	return db.Select("id, title, content, user_id").
+		Where("content LIKE ?", "%[[%").
+		FindInBatches(&notes, linkBatchSize, func(_ *gorm.DB, _ int) error {
+			for _, note := range notes {
+				if err := syncNoteLinks(db, note); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// syncNoteLinks replaces the outgoing links of a note with the ones found in its content.
+func syncNoteLinks(tx *gorm.DB, note *model.Note) error {
+	if err := tx.Where("source_id = ?", note.ID).Delete(&model.NoteLink{}).Error; err != nil {
+		return err
+	}
+
+	titles := wikilink.Titles(note.Content)
+	if len(titles) == 0 {
+		return nil
+	}
+
+	var targets []*model.Note
+	if err := tx.Select("id, title").Where("user_id = ? AND title IN ?", note.UserID, titles).Find(&targets).Error; err != nil {
+		return err
+	}
+	ids := make(map[string]uuid.UUID, len(targets))
+	for _, target := range targets {
+		ids[target.Title] = target.ID
+	}
+
+	links := make([]*model.NoteLink, 0, len(titles))
+	for _, title := range titles {
+		link := &model.NoteLink{SourceID: note.ID, TargetTitle: title, UserID: note.UserID}
+		if id, ok := ids[title]; ok {
+			link.TargetID = &id
+		}
+		links = append(links, link)
+	}
+
+	return tx.Create(&links).Error
+}
+
+// resolveIncomingLinks points the links written with the title of a note at that note.
+func resolveIncomingLinks(tx *gorm.DB, note *model.Note) error {
+	return tx.Model(&model.NoteLink{}).
+		Where("user_id = ? AND target_title = ?", note.UserID, note.Title).
+		UpdateColumn("target_id", note.ID).
+		Error
+}
+
+// renameNoteLinks follows a note renamed from oldTitle. With rewrite, the content of linking notes is
+// changed to the new title and a revision is recorded for each of them; otherwise their links to the
+// old title no longer resolve.
+func renameNoteLinks(tx *gorm.DB, authorID uuid.UUID, note *model.Note, oldTitle string, rewrite bool) error {
+	if rewrite {
+		var sources []*model.Note
+		if err := tx.Where("id IN (?) AND id <> ?",
+			tx.Model(&model.NoteLink{}).Select("source_id").Where("target_id = ?", note.ID),
+			note.ID,
+		).Find(&sources).Error; err != nil {
+			return err
+		}
+
+		for _, source := range sources {
+			content := wikilink.Rewrite(source.Content, oldTitle, note.Title)
+			if content == source.Content {
+				continue
+			}
+			source.Content = content
+			if err := tx.Model(source).UpdateColumn("content", content).Error; err != nil {
+				return err
+			}
+			if err := syncNoteLinks(tx, source); err != nil {
+				return err
+			}
+			if err := createNoteRevision(tx, authorID, source.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Model(&model.NoteLink{}).
+		Where("target_id = ? AND target_title <> ?", note.ID, note.Title).
+		UpdateColumn("target_id", nil).Error; err != nil {
+		return err
+	}
+
+	return resolveIncomingLinks(tx, note)
+}
+
+// Backlinks returns the notes that link to the given note.
+func (p *Postgres) Backlinks(ctx context.Context, userID, noteID uuid.UUID) ([]*model.Note, error) {
+	if _, err := p.GetNoteByID(ctx, userID, noteID); err != nil {
+		return nil, err
+	}
+
+	notes := []*model.Note{}
+	if err := p.db.WithContext(ctx).Preload("Tags").
+		Where("user_id = ? AND id <> ? AND id IN (?)", userID, noteID,
+			p.db.Model(&model.NoteLink{}).Select("source_id").Where("target_id = ?", noteID)).
+		Order("title").
+		Find(&notes).Error; err != nil {
+		return nil, err
+	}
+
+	return notes, nil
+}
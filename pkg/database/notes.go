@@ -3,6 +3,8 @@ package storage
 import (
 	"context"
 	"github.com/box1bs/TelegraphicVault/pkg/model"
+	"github.com/box1bs/TelegraphicVault/pkg/rules"
+	"github.com/box1bs/TelegraphicVault/pkg/wikilink"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -11,29 +13,93 @@ import (
 type NoteFilter struct {
 	UserID uuid.UUID 	`json:"user_id"`
 	Tag    string 		`json:"tag"`
+	Tags   TagQuery 	`json:"tags"`
+	Collection uuid.UUID `json:"collection"`
+	// Recursive includes the subcollections of Collection
+	Recursive bool 		`json:"recursive"`
+	Pinned *bool 		`json:"pinned"`
+	Favorite *bool 		`json:"favorite"`
+	// Type keeps only notes of one type, e.g. model.NoteTypeJournal
+	Type string 		`json:"type"`
 }
 
-func (p *Postgres) CreateNote(ctx context.Context, note model.Note) error {
-	return p.db.WithContext(ctx).Create(&note).Error
+// CreateNote saves a note with the named tags. The tags are attached before tag rules run and the
+// first revision is taken, so that revision records them.
+func (p *Postgres) CreateNote(ctx context.Context, note model.Note, tagNames []string) error {
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&note).Error; err != nil {
+			return err
+		}
+		if err := appendTags(tx, &note, note.UserID, tagNames); err != nil {
+			return err
+		}
+		if err := applyTagRules(tx, &note, note.UserID, rules.NoteItem(&note)); err != nil {
+			return err
+		}
+		if err := syncNoteLinks(tx, &note); err != nil {
+			return err
+		}
+		if err := resolveIncomingLinks(tx, &note); err != nil {
+			return err
+		}
+		return createNoteRevision(tx, note.UserID, note.ID)
+	})
 }
 
 func (p *Postgres) GetNote(ctx context.Context, userID uuid.UUID, title string) (*model.Note, error) {
 	var note model.Note
-	err := p.db.WithContext(ctx).Where("user_id = ? AND title = ?", userID, title).First(&note).Error
+	err := p.db.WithContext(ctx).Preload("Tags").Where("user_id = ? AND title = ?", userID, title).First(&note).Error
 	if err != nil {
 		return nil, err
 	}
 	return &note, nil
 }
 
-func (p *Postgres) UpdateNote(ctx context.Context, userID uuid.UUID, currentTitle, newTitle, content string, newTagNames []string) (*model.Note, error) {
+func (p *Postgres) GetNoteByID(ctx context.Context, userID, id uuid.UUID) (*model.Note, error) {
+	var note model.Note
+	if err := p.db.WithContext(ctx).Preload("Tags").Where("user_id = ? AND id = ?", userID, id).First(&note).Error; err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+func (p *Postgres) SearchNote(ctx context.Context, filter NoteFilter, title string) (*model.Note, error) {
+	var err error
+	if filter.Tags, err = p.resolveTagQuery(ctx, filter.UserID, filter.Tags); err != nil {
+		return nil, err
+	}
+
+	var note model.Note
+	q := p.db.WithContext(ctx).Model(&model.Note{}).
+		Where("user_id = ? AND title = ?", filter.UserID, title)
+	q = p.applyTagQuery(q, filter.UserID, filter.Tags, "notes", "note_tags", "note_id")
+
+	if filter.Type != "" {
+		q = q.Where("type = ?", filter.Type)
+	}
+
+	if err := q.Preload("Tags").First(&note).Error; err != nil {
+		return nil, err
+	}
+
+	return &note, nil
+}
+
+// UpdateNote changes a note found by currentTitle. On a rename with rewriteLinks, [[links]] to the old
+// title in other notes are rewritten to the new one; a new title that links cannot express is rejected
+// with model.ErrInvalidTitle. The tags, the note and its new revision are saved in one transaction.
+func (p *Postgres) UpdateNote(ctx context.Context, userID uuid.UUID, currentTitle, newTitle, content string, newTagNames []string, rewriteLinks bool) (*model.Note, error) {
 	note, err := p.GetNote(ctx, userID, currentTitle)
 	if err != nil {
 		return nil, err
 	}
 
+	if currentTitle != newTitle && rewriteLinks && !wikilink.Linkable(newTitle) {
+		return nil, model.ErrInvalidTitle
+	}
+
 	if currentTitle != newTitle {
-		var exist model.Bookmark
+		var exist model.Note
 		if err := p.db.WithContext(ctx).Model(&model.Note{}).Where("user_id = ? AND title = ?", userID, newTitle).First(&exist).Error; err == nil {
 			return nil, model.ErrAlreadyExists
 		}
@@ -42,45 +108,69 @@ func (p *Postgres) UpdateNote(ctx context.Context, userID uuid.UUID, currentTitl
 	note.Title = newTitle
 	note.Content = content
 
-	if newTagNames != nil {
-		if err := p.updateNoteTags(ctx, note, newTagNames); err != nil {
-			return nil, err
+	err = p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if newTagNames != nil {
+			if err := updateNoteTags(tx, note, newTagNames); err != nil {
+				return err
+			}
+		}
+		// the tags are already written by updateNoteTags
+		if err := tx.Omit("Tags").Save(note).Error; err != nil {
+			return err
+		}
+		if err := applyTagRules(tx, note, userID, rules.NoteItem(note)); err != nil {
+			return err
+		}
+		if err := syncNoteLinks(tx, note); err != nil {
+			return err
 		}
+		if currentTitle != newTitle {
+			if err := renameNoteLinks(tx, userID, note, currentTitle, rewriteLinks); err != nil {
+				return err
+			}
+		}
+		return createNoteRevision(tx, userID, note.ID)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	err = p.db.WithContext(ctx).Save(note).Error
-	return note, err
+	if err := p.db.WithContext(ctx).Model(note).Association("Tags").Find(&note.Tags); err != nil {
+		return nil, err
+	}
+	return note, nil
 }
 
+// DeleteNote moves a note to the trash. Its tags are kept for a restore.
 func (p *Postgres) DeleteNote(ctx context.Context, userID uuid.UUID, title string) error {
-	query := p.db.WithContext(ctx).Where("user_id = ? AND title = ?", userID, title)
-	var note model.Note
-	if err := query.First(&note).Error; err != nil {
-		return err
-	}
-
-	if err := p.db.WithContext(ctx).
-		Model(&model.Tag{}).
-		Where("id IN ?", ExtractTagIDs(note.Tags)).
-		UpdateColumn("count", gorm.Expr("GREATEST(count - ?, 0)", 1)).
-		Error; err != nil {
-		return err
-	}
-
-	result := query.Delete(&note)
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var note model.Note
+		if err := tx.Preload("Tags").Where("user_id = ? AND title = ?", userID, title).First(&note).Error; err != nil {
+			return err
+		}
 
-	if err := result.Error; err != nil {
-		return err
-	}
+		result := tx.Delete(&note)
+		if err := result.Error; err != nil {
+			return err
+		}
 
-	if result.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
-	}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
 
-	return nil
+		return recountTags(tx, ExtractTagIDs(note.Tags))
+	})
 }
 
 func (p *Postgres) ListNotes(ctx context.Context, filter NoteFilter) ([]*model.Note, error) {
+	var err error
+	if filter.Tag, err = p.resolveTagName(ctx, filter.UserID, filter.Tag); err != nil {
+		return nil, err
+	}
+	if filter.Tags, err = p.resolveTagQuery(ctx, filter.UserID, filter.Tags); err != nil {
+		return nil, err
+	}
+
 	var notes []*model.Note
 	query := p.db.WithContext(ctx)
 
@@ -89,12 +179,32 @@ func (p *Postgres) ListNotes(ctx context.Context, filter NoteFilter) ([]*model.N
 	}
 
 	if filter.Tag != "" {
-		query = query.Joins("JOIN note_tags ON note.id = note_tags.note_id").
+		condition, name, descendants := tagSubtreeCondition("tags.name", filter.Tag)
+		query = query.Where("notes.id IN (?)", p.db.Table("note_tags").
+			Select("note_tags.note_id").
 			Joins("JOIN tags ON tags.id = note_tags.tag_id").
-			Where("tags.name = ?", filter.Tag)
+			Where(condition, name, descendants))
+	}
+
+	query = p.applyTagQuery(query, filter.UserID, filter.Tags, "notes", "note_tags", "note_id")
+
+	if filter.Collection != uuid.Nil {
+		query = collectionCondition(query, filter.UserID, filter.Collection, filter.Recursive)
+	}
+
+	if filter.Pinned != nil {
+		query = query.Where("pinned = ?", *filter.Pinned)
+	}
+
+	if filter.Favorite != nil {
+		query = query.Where("favorite = ?", *filter.Favorite)
+	}
+
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
 	}
 
-	err := query.Preload("Tags").Find(&notes).Error
+	err = query.Preload("Tags").Order(pinnedOrder).Find(&notes).Error
 	if err != nil {
 		return nil, err
 	}
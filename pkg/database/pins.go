@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+	"github.com/box1bs/TelegraphicVault/pkg/rank"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// pinnedOrder lists pinned items first in their manual order. Ranks are compared bytewise,
+// independent of the database collation.
+const pinnedOrder = `pinned DESC, pin_rank COLLATE "C", created_at DESC`
+
+type pinState struct {
+	Pinned  bool
+	PinRank string
+}
+
+func getPinState(tx *gorm.DB, items any, userID, id uuid.UUID) (*pinState, error) {
+	var state pinState
+	result := tx.Model(items).Select("pinned, pin_rank").Where("user_id = ? AND id = ?", userID, id).Limit(1).Scan(&state)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &state, nil
+}
+
+// firstPinnedRank returns the rank of the topmost pinned item of a user,
+// or an empty string when nothing is pinned.
+func firstPinnedRank(tx *gorm.DB, items any, userID uuid.UUID) (string, error) {
+	var r *string
+	if err := tx.Model(items).
+		Select(`min(pin_rank COLLATE "C")`).
+		Where("user_id = ? AND pinned", userID).
+		Scan(&r).Error; err != nil {
+		return "", err
+	}
+	if r == nil {
+		return "", nil
+	}
+	return *r, nil
+}
+
+// setFlags pins, unpins or favorites an item. Nil flags are left unchanged;
+// newly pinned items go to the top of the pinned list.
+func setFlags(tx *gorm.DB, items any, userID, id uuid.UUID, pinned, favorite *bool) error {
+	state, err := getPinState(tx, items, userID, id)
+	if err != nil {
+		return err
+	}
+
+	columns := map[string]any{}
+	if favorite != nil {
+		columns["favorite"] = *favorite
+	}
+
+	if pinned != nil && *pinned != state.Pinned {
+		columns["pinned"] = *pinned
+		columns["pin_rank"] = ""
+		if *pinned {
+			first, err := firstPinnedRank(tx, items, userID)
+			if err != nil {
+				return err
+			}
+			if columns["pin_rank"], err = rank.Between("", first); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(columns) == 0 {
+		return nil
+	}
+
+	return tx.Model(items).Where("user_id = ? AND id = ?", userID, id).UpdateColumns(columns).Error
+}
+
+// reorderPinned moves a pinned item between two pinned neighbours. Either neighbour may be nil,
+// in which case the item goes right after "after" or right before "before"; with both nil it
+// goes to the top. Only the moved item gets a new rank.
+func reorderPinned(tx *gorm.DB, items any, userID, id uuid.UUID, after, before *uuid.UUID) error {
+	state, err := getPinState(tx, items, userID, id)
+	if err != nil {
+		return err
+	}
+	if !state.Pinned {
+		return model.ErrInvalidPosition
+	}
+
+	neighbour := func(neighbourID *uuid.UUID) (string, error) {
+		if neighbourID == nil {
+			return "", nil
+		}
+		if *neighbourID == id {
+			return "", model.ErrInvalidPosition
+		}
+		other, err := getPinState(tx, items, userID, *neighbourID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return "", model.ErrInvalidPosition
+			}
+			return "", err
+		}
+		if !other.Pinned {
+			return "", model.ErrInvalidPosition
+		}
+		return other.PinRank, nil
+	}
+
+	lower, err := neighbour(after)
+	if err != nil {
+		return err
+	}
+	upper, err := neighbour(before)
+	if err != nil {
+		return err
+	}
+
+	// with a single neighbour the other bound is the next pinned item on that side
+	others := tx.Model(items).Where("user_id = ? AND pinned AND id <> ?", userID, id)
+	switch {
+	case after != nil && before == nil:
+		var next *string
+		if err := others.Select(`min(pin_rank COLLATE "C")`).Where(`pin_rank COLLATE "C" > ?`, lower).Scan(&next).Error; err != nil {
+			return err
+		}
+		if next != nil {
+			upper = *next
+		}
+	case before != nil && after == nil:
+		var previous *string
+		if err := others.Select(`max(pin_rank COLLATE "C")`).Where(`pin_rank COLLATE "C" < ?`, upper).Scan(&previous).Error; err != nil {
+			return err
+		}
+		if previous != nil {
+			lower = *previous
+		}
+	case after == nil && before == nil:
+		var first *string
+		if err := others.Select(`min(pin_rank COLLATE "C")`).Scan(&first).Error; err != nil {
+			return err
+		}
+		if first != nil {
+			upper = *first
+		}
+	}
+
+	r, err := rank.Between(lower, upper)
+	if err != nil {
+		return model.ErrInvalidPosition
+	}
+
+	return tx.Model(items).Where("user_id = ? AND id = ?", userID, id).UpdateColumn("pin_rank", r).Error
+}
+
+func (p *Postgres) SetNoteFlags(ctx context.Context, userID, id uuid.UUID, pinned, favorite *bool) (*model.Note, error) {
+	if err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return setFlags(tx, &model.Note{}, userID, id, pinned, favorite)
+	}); err != nil {
+		return nil, err
+	}
+	return p.GetNoteByID(ctx, userID, id)
+}
+
+func (p *Postgres) SetBookmarkFlags(ctx context.Context, userID, id uuid.UUID, pinned, favorite *bool) (*model.Bookmark, error) {
+	if err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return setFlags(tx, &model.Bookmark{}, userID, id, pinned, favorite)
+	}); err != nil {
+		return nil, err
+	}
+	return p.GetBookmarkByID(ctx, userID, id)
+}
+
+func (p *Postgres) ReorderNote(ctx context.Context, userID, id uuid.UUID, after, before *uuid.UUID) (*model.Note, error) {
+	if err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return reorderPinned(tx, &model.Note{}, userID, id, after, before)
+	}); err != nil {
+		return nil, err
+	}
+	return p.GetNoteByID(ctx, userID, id)
+}
+
+func (p *Postgres) ReorderBookmark(ctx context.Context, userID, id uuid.UUID, after, before *uuid.UUID) (*model.Bookmark, error) {
+	if err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return reorderPinned(tx, &model.Bookmark{}, userID, id, after, before)
+	}); err != nil {
+		return nil, err
+	}
+	return p.GetBookmarkByID(ctx, userID, id)
+}
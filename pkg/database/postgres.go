@@ -19,10 +19,92 @@ func NewPostgresDB(dsn string) (*Postgres, error) {
         return nil, fmt.Errorf("failed to connect to database: %w", err)
     }
 
-    err = db.AutoMigrate(&model.Bookmark{}, &model.Note{}, &model.Tag{}, &model.User{})
+    err = db.AutoMigrate(&model.Bookmark{}, &model.Note{}, &model.Tag{}, &model.User{}, &model.TagRule{}, &model.TagAlias{}, &model.BookmarkArchive{}, &model.Collection{}, &model.NoteRevision{}, &model.NoteLink{}, &model.Blob{}, &model.Attachment{}, &model.NoteTemplate{}, &model.Migration{})
     if err != nil {
         return nil, fmt.Errorf("failed to run migrations: %w", err)
     }
 
+    // days that got two journal notes before the unique index existed keep the first one as their journal note
+    if err := db.Exec(
+        "UPDATE notes SET type = 'note', journal_date = NULL WHERE type = 'journal' AND journal_date IS NOT NULL AND deleted_at IS NULL " +
+        "AND id NOT IN (SELECT DISTINCT ON (user_id, journal_date) id FROM notes " +
+        "WHERE type = 'journal' AND journal_date IS NOT NULL AND deleted_at IS NULL ORDER BY user_id, journal_date, created_at)",
+    ).Error; err != nil {
+        return nil, fmt.Errorf("failed to deduplicate journal notes: %w", err)
+    }
+
+    for _, index := range []string{
+        "CREATE INDEX IF NOT EXISTS idx_note_tags_tag_id ON note_tags (tag_id)",
+        "CREATE INDEX IF NOT EXISTS idx_bookmark_tags_tag_id ON bookmark_tags (tag_id)",
+        "CREATE INDEX IF NOT EXISTS idx_tags_user_name_pattern ON tags (user_id, name text_pattern_ops)",
+        "CREATE INDEX IF NOT EXISTS idx_bookmarks_document ON bookmarks USING GIN (" + bookmarkDocument + ")",
+        // one journal note per day; notes in the trash do not count
+        "CREATE UNIQUE INDEX IF NOT EXISTS idx_notes_user_journal_date ON notes (user_id, journal_date) WHERE type = 'journal' AND deleted_at IS NULL",
+    } {
+        if err := db.Exec(index).Error; err != nil {
+            return nil, fmt.Errorf("failed to create index: %w", err)
+        }
+    }
+
+    if err := backfillTagOwners(db); err != nil {
+        return nil, fmt.Errorf("failed to assign legacy tags: %w", err)
+    }
+
+    for _, backfill := range []string{
+        // bookmarks imported as read before read statuses existed
+        "UPDATE bookmarks SET read_status = 'read' WHERE read_at IS NOT NULL AND read_status = 'unread'",
+        "UPDATE bookmarks SET reading_minutes = ceil(array_length(regexp_split_to_array(btrim(article_text), '\\s+'), 1) / 200.0) WHERE reading_minutes = 0 AND btrim(article_text) <> ''",
+    } {
+        if err := db.Exec(backfill).Error; err != nil {
+            return nil, fmt.Errorf("failed to backfill bookmarks: %w", err)
+        }
+    }
+
+    // notes created before revisions existed start their history with their current state
+    if err := db.Exec(
+        "INSERT INTO note_revisions (note_id, number, title, content, tags, author_id, author, user_id, created_at) " +
+        "SELECT notes.id, 1, notes.title, notes.content, coalesce((SELECT json_agg(tags.name ORDER BY tags.name)::text " +
+        "FROM note_tags JOIN tags ON tags.id = note_tags.tag_id WHERE note_tags.note_id = notes.id), '[]'), " +
+        "notes.user_id, users.username, notes.user_id, notes.created_at FROM notes JOIN users ON users.id = notes.user_id " +
+        "WHERE NOT EXISTS (SELECT 1 FROM note_revisions WHERE note_revisions.note_id = notes.id)",
+    ).Error; err != nil {
+        return nil, fmt.Errorf("failed to backfill note revisions: %w", err)
+    }
+
+    if err := backfillNormalizedURLs(db); err != nil {
+        return nil, fmt.Errorf("failed to normalize bookmark urls: %w", err)
+    }
+
+    if err := runOnce(db, "renormalize-query-urls", renormalizeQueryURLs); err != nil {
+        return nil, fmt.Errorf("failed to normalize bookmark urls: %w", err)
+    }
+
+    if err := backfillNoteLinks(db); err != nil {
+        return nil, fmt.Errorf("failed to index note links: %w", err)
+    }
+
     return &Postgres{db: db}, nil
-}
\ No newline at end of file
+}
+
+// runOnce applies a data migration that must not repeat on every start and records it under name.
+// Servers starting at the same time wait for each other, so the migration runs exactly once.
+func runOnce(db *gorm.DB, name string, migrate func(tx *gorm.DB) error) error {
+    return db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "migration:" + name).Error; err != nil {
+            return err
+        }
+
+        var applied int64
+        if err := tx.Model(&model.Migration{}).Where("name = ?", name).Count(&applied).Error; err != nil {
+            return err
+        }
+        if applied > 0 {
+            return nil
+        }
+
+        if err := migrate(tx); err != nil {
+            return err
+        }
+        return tx.Create(&model.Migration{Name: name}).Error
+    })
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"slices"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const wordsPerMinute = 200
+
+// orders of the read-later queue
+const (
+	QueueOldest   = "oldest"
+	QueueNewest   = "newest"
+	QueueShortest = "shortest"
+	QueueLongest  = "longest"
+)
+
+var queueOrders = map[string]string{
+	QueueOldest:   "created_at",
+	QueueNewest:   "created_at DESC",
+	QueueShortest: "reading_minutes = 0, reading_minutes, created_at",
+	QueueLongest:  "reading_minutes DESC, created_at",
+}
+
+// SetBookmarkStatus moves a bookmark to another read-later status. Entering reading, read or archived
+// stamps the matching timestamp; going back to unread clears them.
+func (p *Postgres) SetBookmarkStatus(ctx context.Context, userID, id uuid.UUID, status string) (*model.Bookmark, error) {
+	if !slices.Contains(model.ReadStatuses, status) {
+		return nil, model.ErrInvalidStatus
+	}
+
+	columns := map[string]any{"read_status": status}
+	now := gorm.Expr("CURRENT_TIMESTAMP")
+	switch status {
+	case model.ReadStatusUnread:
+		columns["started_at"] = nil
+		columns["read_at"] = nil
+		columns["archived_at"] = nil
+	case model.ReadStatusReading:
+		columns["started_at"] = now
+		columns["read_at"] = nil
+		columns["archived_at"] = nil
+	case model.ReadStatusRead:
+		columns["started_at"] = gorm.Expr("coalesce(started_at, CURRENT_TIMESTAMP)")
+		columns["read_at"] = now
+		columns["archived_at"] = nil
+	case model.ReadStatusArchived:
+		columns["archived_at"] = now
+	}
+
+	result := p.db.WithContext(ctx).Model(&model.Bookmark{}).Where("user_id = ? AND id = ?", userID, id).UpdateColumns(columns)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return p.GetBookmarkByID(ctx, userID, id)
+}
+
+// NextBookmarkToRead returns the next item of the read-later queue: bookmarks already being read come
+// first, then unread ones in the given order.
+func (p *Postgres) NextBookmarkToRead(ctx context.Context, filter BookmarkFilter, order string) (*model.Bookmark, error) {
+	orderBy, ok := queueOrders[order]
+	if !ok {
+		return nil, model.ErrInvalidOrder
+	}
+
+	var err error
+	if filter.Tags, err = p.resolveTagQuery(ctx, filter.UserID, filter.Tags); err != nil {
+		return nil, err
+	}
+
+	query := p.db.WithContext(ctx).Model(&model.Bookmark{}).
+		Where("user_id = ? AND read_status IN ?", filter.UserID, []string{model.ReadStatusUnread, model.ReadStatusReading})
+	query = p.applyTagQuery(query, filter.UserID, filter.Tags, "bookmarks", "bookmark_tags", "bookmark_id")
+
+	var bookmark model.Bookmark
+	if err := query.Preload("Tags").
+		Order("read_status = 'reading' DESC").
+		Order(orderBy).
+		First(&bookmark).Error; err != nil {
+		return nil, err
+	}
+
+	return &bookmark, nil
+}
+
+// CountBookmarksByStatus returns the number of bookmarks in every read-later status, including empty ones.
+func (p *Postgres) CountBookmarksByStatus(ctx context.Context, userID uuid.UUID) (map[string]int64, error) {
+	var rows []struct {
+		ReadStatus string
+		Count      int64
+	}
+	if err := p.db.WithContext(ctx).Model(&model.Bookmark{}).
+		Select("read_status, count(*) AS count").
+		Where("user_id = ?", userID).
+		Group("read_status").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(model.ReadStatuses))
+	for _, status := range model.ReadStatuses {
+		counts[status] = 0
+	}
+	for _, row := range rows {
+		counts[row.ReadStatus] = row.Count
+	}
+
+	return counts, nil
+}
+
+func readingMinutes(words int) int {
+	return (words + wordsPerMinute - 1) / wordsPerMinute
+}
@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// createNoteRevision snapshots the current state of a note unless it matches the latest revision,
+// then prunes old revisions according to the retention settings of the note owner.
+func createNoteRevision(tx *gorm.DB, authorID, noteID uuid.UUID) error {
+	var note model.Note
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", noteID).First(&note).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&note).Association("Tags").Find(&note.Tags); err != nil {
+		return err
+	}
+
+	tags := make([]string, 0, len(note.Tags))
+	for _, tag := range note.Tags {
+		tags = append(tags, tag.Name)
+	}
+	slices.Sort(tags)
+
+	var latest model.NoteRevision
+	err := tx.Where("note_id = ?", noteID).Order("number DESC").Limit(1).Find(&latest).Error
+	if err != nil {
+		return err
+	}
+	if latest.ID != uuid.Nil && latest.Title == note.Title && latest.Content == note.Content && slices.Equal(latest.Tags, tags) {
+		return nil
+	}
+
+	var author model.User
+	if err := tx.Select("username").Where("id = ?", authorID).First(&author).Error; err != nil {
+		return err
+	}
+
+	revision := &model.NoteRevision{
+		NoteID: noteID,
+		Number: latest.Number + 1,
+		Title: note.Title,
+		Content: note.Content,
+		Tags: tags,
+		AuthorID: authorID,
+		Author: author.Username,
+		UserID: note.UserID,
+	}
+	if err := tx.Create(revision).Error; err != nil {
+		return err
+	}
+
+	var owner model.User
+	if err := tx.Select("revision_limit, revision_days").Where("id = ?", note.UserID).First(&owner).Error; err != nil {
+		return err
+	}
+
+	return pruneNoteRevisions(tx.Where("note_id = ?", noteID), owner.RevisionLimit, owner.RevisionDays)
+}
+
+// pruneNoteRevisions deletes the revisions matched by scope that exceed the count limit or are older than
+// the given number of days. The latest revision of every note is always kept.
+func pruneNoteRevisions(scope *gorm.DB, limit, days int) error {
+	if limit <= 0 && days <= 0 {
+		return nil
+	}
+
+	latest := "(SELECT max(number) FROM note_revisions AS newer WHERE newer.note_id = note_revisions.note_id)"
+	query := scope.Where("number < " + latest)
+
+	switch {
+	case limit > 0 && days > 0:
+		query = query.Where("(number <= "+latest+" - ? OR created_at < ?)", limit, time.Now().AddDate(0, 0, -days))
+	case limit > 0:
+		query = query.Where("number <= "+latest+" - ?", limit)
+	default:
+		query = query.Where("created_at < ?", time.Now().AddDate(0, 0, -days))
+	}
+
+	return query.Delete(&model.NoteRevision{}).Error
+}
+
+// ListNoteRevisions returns the revisions of a note, newest first, without their content.
+func (p *Postgres) ListNoteRevisions(ctx context.Context, userID, noteID uuid.UUID) ([]*model.NoteRevision, error) {
+	var revisions []*model.NoteRevision
+	if err := p.db.WithContext(ctx).
+		Omit("content").
+		Where("user_id = ? AND note_id = ?", userID, noteID).
+		Order("number DESC").
+		Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+
+	if len(revisions) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return revisions, nil
+}
+
+func (p *Postgres) GetNoteRevision(ctx context.Context, userID, noteID uuid.UUID, number int) (*model.NoteRevision, error) {
+	var revision model.NoteRevision
+	if err := p.db.WithContext(ctx).
+		Where("user_id = ? AND note_id = ? AND number = ?", userID, noteID, number).
+		First(&revision).Error; err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// RestoreNoteRevision brings back the title, content and tags of an old revision. The restored state
+// is recorded as a new revision, so the history is never rewritten.
+func (p *Postgres) RestoreNoteRevision(ctx context.Context, userID, noteID uuid.UUID, number int) (*model.Note, error) {
+	revision, err := p.GetNoteRevision(ctx, userID, noteID, number)
+	if err != nil {
+		return nil, err
+	}
+
+	note, err := p.GetNoteByID(ctx, userID, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := revision.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return p.UpdateNote(ctx, userID, note.Title, revision.Title, revision.Content, tags, false)
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+	"github.com/box1bs/TelegraphicVault/pkg/rules"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const ruleBatchSize = 200
+
+func (p *Postgres) CreateTagRule(ctx context.Context, rule *model.TagRule) error {
+	if err := rules.Validate(rule); err != nil {
+		return err
+	}
+	return p.db.WithContext(ctx).Create(rule).Error
+}
+
+func (p *Postgres) ListTagRules(ctx context.Context, userID uuid.UUID) ([]*model.TagRule, error) {
+	var tagRules []*model.TagRule
+	if err := p.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at").Find(&tagRules).Error; err != nil {
+		return nil, err
+	}
+	return tagRules, nil
+}
+
+func (p *Postgres) UpdateTagRule(ctx context.Context, rule *model.TagRule) (*model.TagRule, error) {
+	if err := rules.Validate(rule); err != nil {
+		return nil, err
+	}
+
+	var existing model.TagRule
+	if err := p.db.WithContext(ctx).Where("user_id = ? AND id = ?", rule.UserID, rule.ID).First(&existing).Error; err != nil {
+		return nil, err
+	}
+
+	rule.CreatedAt = existing.CreatedAt
+	if err := p.db.WithContext(ctx).Save(rule).Error; err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (p *Postgres) DeleteTagRule(ctx context.Context, userID, id uuid.UUID) error {
+	result := p.db.WithContext(ctx).Where("user_id = ? AND id = ?", userID, id).Delete(&model.TagRule{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// applyTagRules adds the tags of the user's matching rules to owner, a note or a bookmark.
+func applyTagRules(tx *gorm.DB, owner any, userID uuid.UUID, item rules.Item) error {
+	var tagRules []model.TagRule
+	if err := tx.Where("user_id = ? AND enabled = ?", userID, true).Find(&tagRules).Error; err != nil {
+		return err
+	}
+
+	return appendTags(tx, owner, userID, rules.Apply(tagRules, item))
+}
+
+// ApplyTagRules re-evaluates the user's rules against every existing note and bookmark
+// and returns how many items matched at least one rule.
+func (p *Postgres) ApplyTagRules(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var tagRules []model.TagRule
+	if err := p.db.WithContext(ctx).Where("user_id = ? AND enabled = ?", userID, true).Find(&tagRules).Error; err != nil {
+		return 0, err
+	}
+
+	if len(tagRules) == 0 {
+		return 0, nil
+	}
+
+	set := rules.Compile(tagRules)
+
+	var matched int64
+	var notes []*model.Note
+	err := p.db.WithContext(ctx).Where("user_id = ?", userID).FindInBatches(&notes, ruleBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, note := range notes {
+			tags := set.Apply(rules.NoteItem(note))
+			if len(tags) == 0 {
+				continue
+			}
+			if err := p.AddTagToNote(ctx, note, tags); err != nil {
+				return err
+			}
+			matched++
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return matched, err
+	}
+
+	var bookmarks []*model.Bookmark
+	err = p.db.WithContext(ctx).Where("user_id = ?", userID).FindInBatches(&bookmarks, ruleBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, bookmark := range bookmarks {
+			tags := set.Apply(rules.BookmarkItem(bookmark))
+			if len(tags) == 0 {
+				continue
+			}
+			if err := p.AddTagToBookmark(ctx, bookmark, tags); err != nil {
+				return err
+			}
+			matched++
+		}
+		return nil
+	}).Error
+
+	return matched, err
+}
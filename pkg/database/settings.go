@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserSettings holds the per-user preferences to change; nil fields are left as they are.
+type UserSettings struct {
+	RevisionLimit *int `json:"revision_limit"`
+	RevisionDays  *int `json:"revision_days"`
+	Timezone      *string `json:"timezone"`
+	// JournalTemplateID selects the template of new journal notes; an empty string clears it
+	JournalTemplateID *string `json:"journal_template_id"`
+}
+
+// UpdateUserSettings saves the given preferences. New revision retention limits are applied
+// to the existing history right away.
+func (p *Postgres) UpdateUserSettings(ctx context.Context, userID uuid.UUID, settings UserSettings) (*model.User, error) {
+	columns := map[string]any{}
+	if settings.RevisionLimit != nil {
+		if *settings.RevisionLimit < 0 {
+			return nil, model.ErrInvalidSettings
+		}
+		columns["revision_limit"] = *settings.RevisionLimit
+	}
+	if settings.RevisionDays != nil {
+		if *settings.RevisionDays < 0 {
+			return nil, model.ErrInvalidSettings
+		}
+		columns["revision_days"] = *settings.RevisionDays
+	}
+	if settings.Timezone != nil {
+		if *settings.Timezone == "" {
+			return nil, model.ErrInvalidSettings
+		}
+		if _, err := time.LoadLocation(*settings.Timezone); err != nil {
+			return nil, model.ErrInvalidSettings
+		}
+		columns["timezone"] = *settings.Timezone
+	}
+	if settings.JournalTemplateID != nil {
+		columns["journal_template_id"] = nil
+		if *settings.JournalTemplateID != "" {
+			templateID, err := uuid.Parse(*settings.JournalTemplateID)
+			if err != nil {
+				return nil, model.ErrInvalidSettings
+			}
+			if _, err := p.GetTemplate(ctx, userID, templateID); err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil, model.ErrInvalidSettings
+				}
+				return nil, err
+			}
+			columns["journal_template_id"] = templateID
+		}
+	}
+
+	var user model.User
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(columns) > 0 {
+			if err := tx.Model(&model.User{}).Where("id = ?", userID).UpdateColumns(columns).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("id = ?", userID).First(&user).Error; err != nil {
+			return err
+		}
+
+		return pruneNoteRevisions(tx.Where("user_id = ?", userID), user.RevisionLimit, user.RevisionDays)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
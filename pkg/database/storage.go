@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"time"
 	"github.com/box1bs/TelegraphicVault/pkg/model"
 
 	"github.com/google/uuid"
@@ -12,6 +13,12 @@ type Storage interface {
 	tagStorage
 	noteStorage
 	bookmarkStorage
+	ruleStorage
+	collectionStorage
+	archiveStorage
+	trashStorage
+	attachmentStorage
+	templateStorage
 }
 
 type JWTUserStorage interface {
@@ -25,28 +32,118 @@ type UserStorage interface {
 	FindByID(uuid.UUID) (*model.User, error)
 	FindByUsername(string) (*model.User, error)
 	LastLoginUpdate(*model.User) error
+	UpdateUserSettings(context.Context, uuid.UUID, UserSettings) (*model.User, error)
 }
 
 type bookmarkStorage interface {
     CreateBookmark(context.Context, model.Bookmark) error
-	SearchBookmark(context.Context, uuid.UUID, string) ([]model.Bookmark, error)
+	GetBookmarkByID(context.Context, uuid.UUID, uuid.UUID) (*model.Bookmark, error)
+	SearchBookmark(context.Context, BookmarkFilter, string) ([]model.Bookmark, error)
     UpdateBookmark(context.Context, uuid.UUID, string, string, string, []string) (*model.Bookmark, error)
     DeleteBookmark(context.Context, uuid.UUID, string) error
     ListBookmarks(context.Context, BookmarkFilter) ([]*model.Bookmark, error)
+	SaveBookmarkMetadata(context.Context, uuid.UUID, string, string, string, string, string) error
+	SaveBookmarkArticle(context.Context, uuid.UUID, string) error
+	ListBookmarksToCheck(context.Context, time.Time, int) ([]*model.Bookmark, error)
+	SaveLinkCheck(context.Context, uuid.UUID, LinkCheck, int) error
+	ApplyBookmarkRedirect(context.Context, uuid.UUID, uuid.UUID) (*model.Bookmark, error)
+	FindDuplicateBookmarks(context.Context, uuid.UUID) ([]*DuplicateGroup, error)
+	MergeBookmarks(context.Context, uuid.UUID, uuid.UUID, []uuid.UUID) (*model.Bookmark, error)
+	SetBookmarkStatus(context.Context, uuid.UUID, uuid.UUID, string) (*model.Bookmark, error)
+	NextBookmarkToRead(context.Context, BookmarkFilter, string) (*model.Bookmark, error)
+	CountBookmarksByStatus(context.Context, uuid.UUID) (map[string]int64, error)
+	ImportBookmarks(context.Context, uuid.UUID, []*model.Bookmark, ImportOptions) (*ImportReport, error)
+	SetBookmarkFlags(context.Context, uuid.UUID, uuid.UUID, *bool, *bool) (*model.Bookmark, error)
+	ReorderBookmark(context.Context, uuid.UUID, uuid.UUID, *uuid.UUID, *uuid.UUID) (*model.Bookmark, error)
 }
 
 type noteStorage interface {
-    CreateNote(context.Context, model.Note) error
+    CreateNote(context.Context, model.Note, []string) error
     GetNote(context.Context, uuid.UUID, string) (*model.Note, error)
-    UpdateNote(context.Context, uuid.UUID, string, string, string, []string) (*model.Note, error)
+	GetNoteByID(context.Context, uuid.UUID, uuid.UUID) (*model.Note, error)
+    SearchNote(context.Context, NoteFilter, string) (*model.Note, error)
+    UpdateNote(context.Context, uuid.UUID, string, string, string, []string, bool) (*model.Note, error)
     DeleteNote(context.Context, uuid.UUID, string) error
     ListNotes(context.Context, NoteFilter) ([]*model.Note, error)
+	SetNoteFlags(context.Context, uuid.UUID, uuid.UUID, *bool, *bool) (*model.Note, error)
+	ReorderNote(context.Context, uuid.UUID, uuid.UUID, *uuid.UUID, *uuid.UUID) (*model.Note, error)
+	ListNoteRevisions(context.Context, uuid.UUID, uuid.UUID) ([]*model.NoteRevision, error)
+	GetNoteRevision(context.Context, uuid.UUID, uuid.UUID, int) (*model.NoteRevision, error)
+	RestoreNoteRevision(context.Context, uuid.UUID, uuid.UUID, int) (*model.Note, error)
+	Backlinks(context.Context, uuid.UUID, uuid.UUID) ([]*model.Note, error)
+	GetKnowledgeGraph(context.Context, GraphFilter) (*KnowledgeGraph, error)
+	GetJournalNote(context.Context, uuid.UUID, string) (*model.Note, error)
+	ListJournalDays(context.Context, uuid.UUID, string, string) ([]*JournalDay, error)
 }
 
 type tagStorage interface {
-    createTag(context.Context, string) (*model.Tag, error)
+    createTag(context.Context, uuid.UUID, string) (*model.Tag, error)
 	AddTagToNote(context.Context, *model.Note, []string) error
 	AddTagToBookmark(context.Context, *model.Bookmark, []string) error
-	FindByTag(context.Context, string) ([]*model.Note, []*model.Bookmark, error)
+	FindByTag(context.Context, uuid.UUID, string) ([]*model.Note, []*model.Bookmark, error)
+	SearchByTags(context.Context, uuid.UUID, TagQuery) ([]*model.Note, []*model.Bookmark, error)
 	GetPopularTags(context.Context, TagFilter, int) ([]*model.Tag, error)
-}
\ No newline at end of file
+	ListTags(context.Context, TagFilter) ([]*model.Tag, error)
+	UpdateTag(context.Context, uuid.UUID, string, string, *string, *string) (*model.Tag, error)
+	MergeTags(context.Context, uuid.UUID, string, string) (*model.Tag, error)
+	DeleteTag(context.Context, uuid.UUID, string) error
+	GetTagTree(context.Context, uuid.UUID) ([]*TagNode, error)
+	SuggestTags(context.Context, uuid.UUID, string, int) ([]*model.Tag, error)
+	SuggestTagsForContent(context.Context, uuid.UUID, string, int) ([]*model.Tag, error)
+	ListTagAliases(context.Context, uuid.UUID) ([]*TagAliasSet, error)
+	SetTagAliases(context.Context, uuid.UUID, string, []string) (*TagAliasSet, error)
+	DeleteTagAlias(context.Context, uuid.UUID, string) error
+	GetTagCooccurrences(context.Context, uuid.UUID) ([]TagPair, error)
+	GetRelatedTags(context.Context, uuid.UUID, string, int) ([]*RelatedTag, error)
+}
+
+type ruleStorage interface {
+	CreateTagRule(context.Context, *model.TagRule) error
+	ListTagRules(context.Context, uuid.UUID) ([]*model.TagRule, error)
+	UpdateTagRule(context.Context, *model.TagRule) (*model.TagRule, error)
+	DeleteTagRule(context.Context, uuid.UUID, uuid.UUID) error
+	ApplyTagRules(context.Context, uuid.UUID) (int64, error)
+}
+
+type archiveStorage interface {
+	SaveBookmarkArchive(context.Context, *model.BookmarkArchive, int64) error
+	GetBookmarkArchive(context.Context, uuid.UUID, uuid.UUID) (*model.BookmarkArchive, error)
+	DeleteBookmarkArchive(context.Context, uuid.UUID, uuid.UUID) error
+	GetArchiveUsage(context.Context, uuid.UUID) (*ArchiveUsage, error)
+}
+
+type collectionStorage interface {
+	CreateCollection(context.Context, *model.Collection) error
+	ListCollections(context.Context, uuid.UUID) ([]*CollectionNode, error)
+	UpdateCollection(context.Context, uuid.UUID, uuid.UUID, string, string) (*model.Collection, error)
+	MoveCollection(context.Context, uuid.UUID, uuid.UUID, *uuid.UUID, int) (*model.Collection, error)
+	DeleteCollection(context.Context, uuid.UUID, uuid.UUID, string) error
+	MoveToCollection(context.Context, uuid.UUID, *uuid.UUID, []uuid.UUID, []uuid.UUID) error
+}
+
+type trashStorage interface {
+	ListTrash(context.Context, uuid.UUID) (*Trash, error)
+	RestoreNote(context.Context, uuid.UUID, uuid.UUID) (*model.Note, error)
+	RestoreBookmark(context.Context, uuid.UUID, uuid.UUID) (*model.Bookmark, error)
+	PurgeNote(context.Context, uuid.UUID, uuid.UUID) error
+	PurgeBookmark(context.Context, uuid.UUID, uuid.UUID) error
+	EmptyTrash(context.Context, uuid.UUID) error
+	PurgeTrash(context.Context, time.Time) error
+}
+
+type attachmentStorage interface {
+	CreateAttachment(context.Context, *model.Attachment, int64, func() error, func() error) error
+	ListAttachments(context.Context, uuid.UUID, uuid.UUID) ([]*model.Attachment, error)
+	GetAttachment(context.Context, uuid.UUID, uuid.UUID) (*model.Attachment, error)
+	DeleteAttachment(context.Context, uuid.UUID, uuid.UUID) error
+	GetAttachmentUsage(context.Context, uuid.UUID) (*AttachmentUsage, error)
+	PurgeOrphanBlobs(context.Context, func(string) error) (int, error)
+}
+
+type templateStorage interface {
+	CreateTemplate(context.Context, *model.NoteTemplate) error
+	ListTemplates(context.Context, uuid.UUID) ([]*model.NoteTemplate, error)
+	GetTemplate(context.Context, uuid.UUID, uuid.UUID) (*model.NoteTemplate, error)
+	UpdateTemplate(context.Context, *model.NoteTemplate) (*model.NoteTemplate, error)
+	DeleteTemplate(context.Context, uuid.UUID, uuid.UUID) error
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	"github.com/google/uuid"
+)
+
+const maxSuggestionPhraseWords = 3
+
+// SuggestTags completes a partially typed tag. Tags are matched by the beginning of their full path
+// or of any path segment and ranked by how often and how recently the user applied them.
+func (p *Postgres) SuggestTags(ctx context.Context, userID uuid.UUID, prefix string, limit int) ([]*model.Tag, error) {
+	var tags []*model.Tag
+	prefix = escapeLike(normalizeName(prefix))
+
+	query := p.db.WithContext(ctx).Where("user_id = ?", userID)
+	if prefix != "" {
+		query = query.Where("(name LIKE ? ESCAPE '\\' OR name LIKE ? ESCAPE '\\')", prefix + "%", "%" + tagPathSeparator + prefix + "%")
+	}
+
+	if err := query.
+		Order("count DESC").
+		Order("last_used_at DESC NULLS LAST").
+		Order("name").
+		Limit(limit).
+		Find(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// SuggestTagsForContent proposes existing tags whose name, or last path segment, occurs in text
+// as a word or a phrase of up to three words. The segment is split into words the same way the text is,
+// so a tag like "node.js" is found by "Node.js" or "node js".
+func (p *Postgres) SuggestTagsForContent(ctx context.Context, userID uuid.UUID, text string, limit int) ([]*model.Tag, error) {
+	var tags []*model.Tag
+	if err := p.db.WithContext(ctx).Where("user_id = ?", userID).Find(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	phrases := extractPhrases(text)
+	scores := make(map[*model.Tag]int)
+	for _, tag := range tags {
+		key := tag.Name
+		if i := strings.LastIndex(key, tagPathSeparator); i >= 0 {
+			key = key[i+1:]
+		}
+		key = strings.Join(splitWords(key), "-")
+		if key == "" {
+			continue
+		}
+		if score := phrases[key]; score > 0 {
+			scores[tag] = score
+		}
+	}
+
+	suggested := make([]*model.Tag, 0, len(scores))
+	for tag := range scores {
+		suggested = append(suggested, tag)
+	}
+
+	sort.Slice(suggested, func(i, j int) bool {
+		a, b := suggested[i], suggested[j]
+		if scores[a] != scores[b] {
+			return scores[a] > scores[b]
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Name < b.Name
+	})
+
+	if limit > 0 && len(suggested) > limit {
+		suggested = suggested[:limit]
+	}
+
+	return suggested, nil
+}
+
+// extractPhrases counts words and dash-joined word sequences of text in the form tags are normalized to.
+func extractPhrases(text string) map[string]int {
+	words := splitWords(text)
+
+	phrases := make(map[string]int)
+	for i := range words {
+		for n := 1; n <= maxSuggestionPhraseWords && i+n <= len(words); n++ {
+			phrase := strings.Join(words[i:i+n], "-")
+			phrases[phrase]++
+			if singular := strings.TrimSuffix(phrase, "s"); len(singular) > 2 && singular != phrase {
+				phrases[singular]++
+			}
+		}
+	}
+
+	return phrases
+}
+
+// splitWords lowercases text and splits it on every rune that is not a letter or a digit.
+func splitWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"slices"
 	"github.com/box1bs/TelegraphicVault/pkg/model"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -15,98 +16,134 @@ type TagFilter struct {
 	UserID 	uuid.UUID `json:"user_id"`
 }
 
-func (p *Postgres) createTag(ctx context.Context, name string) (*model.Tag, error) {
-	tag := &model.Tag{ Name: normalizeName(name) }
-	if err := p.db.WithContext(ctx).FirstOrCreate(tag, model.Tag{Name: normalizeName(name)}).Error; err != nil {
-		return nil, err
-	}
+func (p *Postgres) createTag(ctx context.Context, userID uuid.UUID, name string) (*model.Tag, error) {
+	return firstOrCreateTag(p.db.WithContext(ctx), userID, name)
+}
 
-	if tag.Count > 0 {
-		if err := p.db.WithContext(ctx).Model(tag).UpdateColumn("count", gorm.Expr("count + ?", 1)).Error; err != nil {
-			return nil, err
-		}
+func firstOrCreateTag(tx *gorm.DB, userID uuid.UUID, name string) (*model.Tag, error) {
+	tag := &model.Tag{ Name: normalizeName(name), UserID: userID }
+	if err := tx.FirstOrCreate(tag, model.Tag{Name: tag.Name, UserID: userID}).Error; err != nil {
+		return nil, err
 	}
 
 	return tag, nil
 }
 
-func (p *Postgres) AddTagToNote(ctx context.Context, note *model.Note, tagNames []string) error {
+// appendTags links the named tags to owner (a note or a bookmark) and refreshes their counters.
+func appendTags(tx *gorm.DB, owner any, userID uuid.UUID, tagNames []string) error {
+	tagNames, err := resolveAliases(tx, userID, tagNames)
+	if err != nil {
+		return err
+	}
+
 	var tags []model.Tag
-	for _, name := range tagNames {
-		tag, err := p.createTag(ctx, name)
+	for _, name := range removeDuplicates(tagNames) {
+		if name == "" {
+			continue
+		}
+		tag, err := firstOrCreateTag(tx, userID, name)
 		if err != nil {
 			return err
 		}
 		tags = append(tags, *tag)
 	}
 
-	return p.db.WithContext(ctx).Model(note).Association("Tags").Append(tags)
-}
+	if len(tags) == 0 {
+		return nil
+	}
 
-func (p *Postgres) updateNoteTags(ctx context.Context, note *model.Note, newTagNames []string) error {
-	newTagNames = removeDuplicates(newTagNames)
+	if err := tx.Model(owner).Association("Tags").Append(tags); err != nil {
+		return err
+	}
 
-	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		var removingTags []model.Tag
-		for _, tag := range note.Tags {
-			if !slices.Contains(newTagNames, tag.Name) {
-				removingTags = append(removingTags, tag)
-			} else {
-				newTagNames = remove(newTagNames, tag.Name)
-			}
-		}
+	if err := tx.Model(&model.Tag{}).
+		Where("id IN ?", ExtractTagIDs(tags)).
+		UpdateColumn("last_used_at", gorm.Expr("CURRENT_TIMESTAMP")).
+		Error; err != nil {
+		return err
+	}
 
-		if len(removingTags) > 0 {
-			if err := tx.Model(note).Association("Tags").Delete(removingTags); err != nil {
-				return err
-			}
+	return recountTags(tx, ExtractTagIDs(tags))
+}
 
-			if err := p.db.WithContext(ctx).
-				Model(&model.Tag{}).
-				Where("id IN ?", ExtractTagIDs(removingTags)).
-				UpdateColumn("count", gorm.Expr("GREATEST(count - ?, 0)", 1)).
-				Error; err != nil {
-				return err
-			}
-		}
+// recountTags recalculates the usage counter of the given tags from the join tables.
+// Items in the trash keep their tags but are not counted.
+func recountTags(tx *gorm.DB, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
 
-		if len(newTagNames) > 0 {
-			if err := p.AddTagToNote(ctx, note, newTagNames); err != nil {
-				return err
-			}
-		}
+	return tx.Model(&model.Tag{}).
+		Where("id IN ?", ids).
+		UpdateColumn("count", gorm.Expr(
+			"(SELECT COUNT(*) FROM note_tags JOIN notes ON notes.id = note_tags.note_id " +
+			"WHERE note_tags.tag_id = tags.id AND notes.deleted_at IS NULL) + " +
+			"(SELECT COUNT(*) FROM bookmark_tags JOIN bookmarks ON bookmarks.id = bookmark_tags.bookmark_id " +
+			"WHERE bookmark_tags.tag_id = tags.id AND bookmarks.deleted_at IS NULL)",
+		)).
+		Error
+}
 
-		return nil
+// AddTagToNote adds the named tags to a note and records the result as a new revision.
+func (p *Postgres) AddTagToNote(ctx context.Context, note *model.Note, tagNames []string) error {
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := appendTags(tx, note, note.UserID, tagNames); err != nil {
+			return err
+		}
+		return createNoteRevision(tx, note.UserID, note.ID)
 	})
+}
 
+// updateNoteTags sets the tags of a note loaded with its Tags to newTagNames within tx.
+func updateNoteTags(tx *gorm.DB, note *model.Note, newTagNames []string) error {
+	newTagNames, err := resolveAliases(tx, note.UserID, newTagNames)
 	if err != nil {
 		return err
 	}
+	newTagNames = removeDuplicates(newTagNames)
 
-	if err := p.db.WithContext(ctx).Preload("Tags").First(note, note.ID).Error; err != nil {
-		return err
+	var removingTags []model.Tag
+	for _, tag := range note.Tags {
+		if !slices.Contains(newTagNames, tag.Name) {
+			removingTags = append(removingTags, tag)
+		} else {
+			newTagNames = remove(newTagNames, tag.Name)
+		}
 	}
 
-	return err
-}
+	if len(removingTags) > 0 {
+		if err := tx.Model(note).Association("Tags").Delete(removingTags); err != nil {
+			return err
+		}
 
-func (p *Postgres) AddTagToBookmark(ctx context.Context, bookmark *model.Bookmark, tagNames []string) error {
-	var tags []model.Tag
-	for _, name := range tagNames {
-		tag, err := p.createTag(ctx, name)
-		if err != nil {
+		if err := recountTags(tx, ExtractTagIDs(removingTags)); err != nil {
+			return err
+		}
+	}
+
+	if len(newTagNames) > 0 {
+		if err := appendTags(tx, note, note.UserID, newTagNames); err != nil {
 			return err
 		}
-		tags = append(tags, *tag)
 	}
 
-	return p.db.WithContext(ctx).Model(bookmark).Association("Tags").Append(tags)
+	return nil
 }
 
-func (p *Postgres) updateBookmarkTags(ctx context.Context, bookmark *model.Bookmark, newTagNames []string) error {
-	newTagNames = removeDuplicates(newTagNames)
+func (p *Postgres) AddTagToBookmark(ctx context.Context, bookmark *model.Bookmark, tagNames []string) error {
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return appendTags(tx, bookmark, bookmark.UserID, tagNames)
+	})
+}
 
+func (p *Postgres) updateBookmarkTags(ctx context.Context, bookmark *model.Bookmark, newTagNames []string) error {
 	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		newTagNames, err := resolveAliases(tx, bookmark.UserID, newTagNames)
+		if err != nil {
+			return err
+		}
+		newTagNames = removeDuplicates(newTagNames)
+
 		var removingTags []model.Tag
 		for _, tag := range bookmark.Tags {
 			if !slices.Contains(newTagNames, tag.Name) {
@@ -121,17 +158,13 @@ func (p *Postgres) updateBookmarkTags(ctx context.Context, bookmark *model.Bookm
 				return err
 			}
 
-			if err := p.db.WithContext(ctx).
-				Model(&model.Tag{}).
-				Where("id IN ?", ExtractTagIDs(removingTags)).
-				UpdateColumn("count", gorm.Expr("GREATEST(count - ?, 0)", 1)).
-				Error; err != nil {
+			if err := recountTags(tx, ExtractTagIDs(removingTags)); err != nil {
 				return err
 			}
 		}
 
 		if len(newTagNames) > 0 {
-			if err := p.AddTagToBookmark(ctx, bookmark, newTagNames); err != nil {
+			if err := appendTags(tx, bookmark, bookmark.UserID, newTagNames); err != nil {
 				return err
 			}
 		}
@@ -173,23 +206,36 @@ func removeDuplicates(names []string) []string {
     return result
 }
 
-func (p *Postgres) FindByTag(ctx context.Context, tagName string) ([]*model.Note, []*model.Bookmark, error) {
+func (p *Postgres) FindByTag(ctx context.Context, userID uuid.UUID, tagName string) ([]*model.Note, []*model.Bookmark, error) {
 	var notes []*model.Note
 	var bookmarks []*model.Bookmark
-	var tag model.Tag
+	var tagIDs []uuid.UUID
 
-	if err := p.db.WithContext(ctx).Where("name = ?", normalizeName(tagName)).First(&tag).Error; err != nil {
+	tagName, err := p.resolveTagName(ctx, userID, tagName)
+	if err != nil {
 		return nil, nil, err
 	}
 
-	if err := p.db.Joins("JOIN note_tags ON notes.id = note_tags.note_id").
-		Where("note_tags.tag_id = ?", tag.ID).
+	condition, name, descendants := tagSubtreeCondition("name", tagName)
+	if err := p.db.WithContext(ctx).Model(&model.Tag{}).
+		Where("user_id = ?", userID).
+		Where(condition, name, descendants).
+		Pluck("id", &tagIDs).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if len(tagIDs) == 0 {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+
+	if err := p.db.WithContext(ctx).
+		Where("id IN (?)", p.db.Table("note_tags").Select("note_id").Where("tag_id IN ?", tagIDs)).
 		Find(&notes).Error; err != nil {
 		return nil, nil, err
 	}
 
-	if err := p.db.Joins("JOIN bookmark_tags ON bookmarks.id = bookmark_tags.bookmark_id").
-		Where("bookmark_tags.tag_id = ?", tag.ID).
+	if err := p.db.WithContext(ctx).
+		Where("id IN (?)", p.db.Table("bookmark_tags").Select("bookmark_id").Where("tag_id IN ?", tagIDs)).
 		Find(&bookmarks).Error; err != nil {
 		return nil, nil, err
 	}
@@ -197,31 +243,79 @@ func (p *Postgres) FindByTag(ctx context.Context, tagName string) ([]*model.Note
 	return notes, bookmarks, nil
 }
 
-func (p *Postgres) SerchByTags(ctx context.Context, tagNames []string) ([]*model.Note, []*model.Bookmark, error) {
-	var notes []*model.Note
-	var bookmarks []*model.Bookmark
-	var tags []model.Tag
+// TagQuery filters items by tags. Every tag also matches its descendants.
+type TagQuery struct {
+	All 	[]string `json:"tags_all"`
+	Any 	[]string `json:"tags_any"`
+	None 	[]string `json:"tags_none"`
+}
 
-	for _, name := range tagNames {
-		tag := model.Tag{Name: normalizeName(name)}
-		tags = append(tags, tag)
+func (q TagQuery) IsEmpty() bool {
+	return len(q.All) == 0 && len(q.Any) == 0 && len(q.None) == 0
+}
+
+// applyTagQuery restricts query to rows of table whose tags, linked through joinTable.column, satisfy q.
+func (p *Postgres) applyTagQuery(query *gorm.DB, userID uuid.UUID, q TagQuery, table, joinTable, column string) *gorm.DB {
+	taggedWith := func(names []string) *gorm.DB {
+		var conditions []string
+		var args []any
+		for _, name := range normalizeNames(names) {
+			if name == "" {
+				continue
+			}
+			condition, exact, descendants := tagSubtreeCondition("tags.name", name)
+			conditions = append(conditions, condition)
+			args = append(args, exact, descendants)
+		}
+		if len(conditions) == 0 {
+			return nil
+		}
+
+		subquery := p.db.Table(joinTable).
+			Select(joinTable + "." + column).
+			Joins("JOIN tags ON tags.id = " + joinTable + ".tag_id").
+			Where("(" + strings.Join(conditions, " OR ") + ")", args...)
+		if userID != uuid.Nil {
+			subquery = subquery.Where("tags.user_id = ?", userID)
+		}
+		return subquery
 	}
 
-	if err := p.db.WithContext(ctx).Where("name IN ?", tags).Find(&tags).Error; err != nil { // overwrites tags
-		return nil, nil, err
+	for _, name := range q.All {
+		if subquery := taggedWith([]string{name}); subquery != nil {
+			query = query.Where(table + ".id IN (?)", subquery)
+		}
 	}
 
-	if len(tags) == 0 {
-		return nil, nil, nil
+	if subquery := taggedWith(q.Any); subquery != nil {
+		query = query.Where(table + ".id IN (?)", subquery)
 	}
 
-	if err := p.db.Joins("JOIN note_tags ON notes.id = note_tags.note_id").
-		Where("note_tags.tag_id IN ?", tags).Find(&notes).Error; err != nil {
+	if subquery := taggedWith(q.None); subquery != nil {
+		query = query.Where(table + ".id NOT IN (?)", subquery)
+	}
+
+	return query
+}
+
+func (p *Postgres) SearchByTags(ctx context.Context, userID uuid.UUID, q TagQuery) ([]*model.Note, []*model.Bookmark, error) {
+	var notes []*model.Note
+	var bookmarks []*model.Bookmark
+
+	q, err := p.resolveTagQuery(ctx, userID, q)
+	if err != nil {
 		return nil, nil, err
 	}
 
-	if err := p.db.Joins("JOIN bookmark_tags ON bookmarks.id = bookmark_tags.bookmark_id").
-		Where("bookmark_tags.tag_id IN ?", tags).Find(&bookmarks).Error; err != nil {
+	noteQuery := p.db.WithContext(ctx).Model(&model.Note{}).Where("notes.user_id = ?", userID)
+	if err := p.applyTagQuery(noteQuery, userID, q, "notes", "note_tags", "note_id").
+		Preload("Tags").Find(&notes).Error; err != nil {
+		return nil, nil, err
+	}
+
+	bookmarkQuery := p.db.WithContext(ctx).Model(&model.Bookmark{}).Where("bookmarks.user_id = ?", userID)
+	if err := p.applyTagQuery(bookmarkQuery, userID, q, "bookmarks", "bookmark_tags", "bookmark_id").
+		Preload("Tags").Find(&bookmarks).Error; err != nil {
 		return nil, nil, err
 	}
 
@@ -238,7 +332,7 @@ func (p* Postgres) GetPopularTags(ctx context.Context, filter TagFilter, limit i
 		return nil, errors.New("user_id is required")
 	}
 
-	err := query.Order("counter DESC").Limit(limit).Find(&tags).Error
+	err := query.Order("count DESC").Limit(limit).Find(&tags).Error
 	if err != nil {
 		return nil, err
 	}
@@ -246,8 +340,406 @@ func (p* Postgres) GetPopularTags(ctx context.Context, filter TagFilter, limit i
 	return tags, nil
 }
 
+func (p *Postgres) ListTags(ctx context.Context, filter TagFilter) ([]*model.Tag, error) {
+	var tags []*model.Tag
+	query := p.db.WithContext(ctx)
+
+	if filter.UserID != uuid.Nil {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+
+	if err := query.Order("count DESC, name").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+type TagNode struct {
+	Name 		string 		`json:"name"`
+	Path 		string 		`json:"path"`
+	Color 		string 		`json:"color,omitempty"`
+	Description string 		`json:"description,omitempty"`
+	Count 		int64 		`json:"count"`
+	Total 		int64 		`json:"total"`
+	Children 	[]*TagNode 	`json:"children,omitempty"`
+}
+
+// GetTagTree arranges the user's tags by path. Count holds the items tagged with the node itself,
+// Total the distinct items tagged with the node or any of its descendants.
+func (p *Postgres) GetTagTree(ctx context.Context, userID uuid.UUID) ([]*TagNode, error) {
+	var tags []*model.Tag
+	if err := p.db.WithContext(ctx).Where("user_id = ?", userID).Order("name").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	var usages []struct {
+		Name 	string
+		Item 	string
+	}
+	if err := p.db.WithContext(ctx).Raw(
+		"SELECT tags.name, 'n:' || note_tags.note_id AS item FROM note_tags JOIN tags ON tags.id = note_tags.tag_id " +
+		"JOIN notes ON notes.id = note_tags.note_id WHERE tags.user_id = ? AND notes.deleted_at IS NULL " +
+		"UNION ALL " +
+		"SELECT tags.name, 'b:' || bookmark_tags.bookmark_id AS item FROM bookmark_tags JOIN tags ON tags.id = bookmark_tags.tag_id " +
+		"JOIN bookmarks ON bookmarks.id = bookmark_tags.bookmark_id WHERE tags.user_id = ? AND bookmarks.deleted_at IS NULL",
+		userID, userID,
+	).Scan(&usages).Error; err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]*TagNode)
+	var roots []*TagNode
+	var node func(path string) *TagNode
+	node = func(path string) *TagNode {
+		if n, ok := nodes[path]; ok {
+			return n
+		}
+		n := &TagNode{Name: path, Path: path}
+		nodes[path] = n
+		if i := strings.LastIndex(path, tagPathSeparator); i >= 0 {
+			n.Name = path[i+1:]
+			parent := node(path[:i])
+			parent.Children = append(parent.Children, n)
+		} else {
+			roots = append(roots, n)
+		}
+		return n
+	}
+
+	for _, tag := range tags {
+		n := node(tag.Name)
+		n.Color = tag.Color
+		n.Description = tag.Description
+		n.Count = tag.Count
+	}
+
+	items := make(map[string]map[string]struct{})
+	for _, usage := range usages {
+		path := usage.Name
+		for {
+			if items[path] == nil {
+				items[path] = make(map[string]struct{})
+			}
+			items[path][usage.Item] = struct{}{}
+
+			i := strings.LastIndex(path, tagPathSeparator)
+			if i < 0 {
+				break
+			}
+			path = path[:i]
+		}
+	}
+
+	for path, n := range nodes {
+		n.Total = int64(len(items[path]))
+	}
+
+	return roots, nil
+}
+
+func (p *Postgres) getTag(tx *gorm.DB, userID uuid.UUID, name string) (*model.Tag, error) {
+	var tag model.Tag
+	if err := tx.Where("user_id = ? AND name = ?", userID, normalizeName(name)).First(&tag).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// UpdateTag renames a tag and changes its color and description. A nil color or description
+// keeps the current value.
+func (p *Postgres) UpdateTag(ctx context.Context, userID uuid.UUID, currentName, newName string, color, description *string) (*model.Tag, error) {
+	var tag *model.Tag
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		tag, err = p.getTag(tx, userID, currentName)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// a parent that only exists as a prefix of its children is materialized on first edit
+			tag, err = p.materializeParentTag(tx, userID, currentName)
+		}
+		if err != nil {
+			return err
+		}
+
+		if newName != "" && normalizeName(newName) != tag.Name {
+			if err := p.moveTag(tx, tag, normalizeName(newName)); err != nil {
+				return err
+			}
+		}
+
+		if color != nil {
+			tag.Color = *color
+		}
+		if description != nil {
+			tag.Description = *description
+		}
+
+		return tx.Save(tag).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+func (p *Postgres) materializeParentTag(tx *gorm.DB, userID uuid.UUID, name string) (*model.Tag, error) {
+	name = normalizeName(name)
+	if name == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var descendants int64
+	if err := tx.Model(&model.Tag{}).
+		Where("user_id = ? AND name LIKE ? ESCAPE '\\'", userID, escapeLike(name) + tagPathSeparator + "%").
+		Count(&descendants).Error; err != nil {
+		return nil, err
+	}
+
+	if descendants == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return firstOrCreateTag(tx, userID, name)
+}
+
+// moveTag renames tag to newPath and re-roots all of its descendants under the new path.
+func (p *Postgres) moveTag(tx *gorm.DB, tag *model.Tag, newPath string) error {
+	if newPath == "" || strings.HasPrefix(newPath, tag.Name + tagPathSeparator) {
+		return model.ErrInvalidTag
+	}
+
+	var descendants []string
+	if err := tx.Model(&model.Tag{}).
+		Where("user_id = ? AND name LIKE ? ESCAPE '\\'", tag.UserID, escapeLike(tag.Name) + tagPathSeparator + "%").
+		Pluck("name", &descendants).Error; err != nil {
+		return err
+	}
+
+	targets := []string{newPath}
+	for _, name := range descendants {
+		targets = append(targets, newPath + strings.TrimPrefix(name, tag.Name))
+	}
+
+	var conflicts int64
+	if err := tx.Model(&model.Tag{}).
+		Where("user_id = ? AND name IN ?", tag.UserID, targets).
+		Count(&conflicts).Error; err != nil {
+		return err
+	}
+
+	if conflicts > 0 {
+		return model.ErrAlreadyExists
+	}
+
+	if err := tx.Model(&model.TagAlias{}).
+		Where("user_id = ? AND alias IN ?", tag.UserID, targets).
+		Count(&conflicts).Error; err != nil {
+		return err
+	}
+
+	if conflicts > 0 {
+		return model.ErrAlreadyExists
+	}
+
+	if len(descendants) > 0 {
+		if err := tx.Model(&model.Tag{}).
+			Where("user_id = ? AND name LIKE ? ESCAPE '\\'", tag.UserID, escapeLike(tag.Name) + tagPathSeparator + "%").
+			// substr counts characters, not bytes
+			UpdateColumn("name", gorm.Expr("? || substr(name, ?)", newPath, utf8.RuneCountInString(tag.Name) + 1)).
+			Error; err != nil {
+			return err
+		}
+	}
+
+	tag.Name = newPath
+	return nil
+}
+
+func (p *Postgres) MergeTags(ctx context.Context, userID uuid.UUID, sourceName, targetName string) (*model.Tag, error) {
+	if normalizeName(sourceName) == normalizeName(targetName) {
+		return nil, model.ErrInvalidTag
+	}
+
+	var target *model.Tag
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		source, err := p.getTag(tx, userID, sourceName)
+		if err != nil {
+			return err
+		}
+
+		target, err = p.getTag(tx, userID, targetName)
+		if err != nil {
+			return err
+		}
+
+		if err := mergeTagInto(tx, source, target); err != nil {
+			return err
+		}
+
+		return tx.First(target, target.ID).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return target, nil
+}
+
+// mergeTagInto moves every item and alias of source to target and deletes source.
+func mergeTagInto(tx *gorm.DB, source, target *model.Tag) error {
+	for _, table := range []struct{ name, column string }{
+		{"note_tags", "note_id"},
+		{"bookmark_tags", "bookmark_id"},
+	} {
+		if err := tx.Exec(
+			"INSERT INTO " + table.name + " (" + table.column + ", tag_id) " +
+			"SELECT " + table.column + ", ? FROM " + table.name + " WHERE tag_id = ? ON CONFLICT DO NOTHING",
+			target.ID, source.ID,
+		).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Exec("DELETE FROM " + table.name + " WHERE tag_id = ?", source.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Model(&model.TagAlias{}).Where("tag_id = ?", source.ID).UpdateColumn("tag_id", target.ID).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Delete(source).Error; err != nil {
+		return err
+	}
+
+	return recountTags(tx, []uuid.UUID{target.ID})
+}
+
+func (p *Postgres) DeleteTag(ctx context.Context, userID uuid.UUID, name string) error {
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tag, err := p.getTag(tx, userID, name)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Exec("DELETE FROM note_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Exec("DELETE FROM bookmark_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("tag_id = ?", tag.ID).Delete(&model.TagAlias{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(tag).Error
+	})
+}
+
+// backfillTagOwners splits the tags created before tags belonged to users: every owner of a note
+// or a bookmark with a legacy tag gets its own tag of that name, and the legacy tag is removed.
+func backfillTagOwners(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var legacy []*model.Tag
+		if err := tx.Where("user_id IS NULL").Find(&legacy).Error; err != nil {
+			return err
+		}
+
+		for _, tag := range legacy {
+			for _, table := range []struct{ name, column, owners string }{
+				{"note_tags", "note_id", "notes"},
+				{"bookmark_tags", "bookmark_id", "bookmarks"},
+			} {
+				var userIDs []uuid.UUID
+				if err := tx.Raw(
+					"SELECT DISTINCT " + table.owners + ".user_id FROM " + table.name + " JOIN " + table.owners +
+					" ON " + table.owners + ".id = " + table.name + "." + table.column + " WHERE " + table.name + ".tag_id = ?",
+					tag.ID,
+				).Scan(&userIDs).Error; err != nil {
+					return err
+				}
+
+				for _, userID := range userIDs {
+					if normalizeName(tag.Name) == "" {
+						break
+					}
+					owned, err := firstOrCreateTag(tx, userID, tag.Name)
+					if err != nil {
+						return err
+					}
+
+					if err := tx.Model(owned).Where("color = ''").UpdateColumn("color", tag.Color).Error; err != nil {
+						return err
+					}
+					if err := tx.Model(owned).Where("description = ''").UpdateColumn("description", tag.Description).Error; err != nil {
+						return err
+					}
+
+					if err := tx.Exec(
+						"INSERT INTO " + table.name + " (" + table.column + ", tag_id) " +
+						"SELECT " + table.name + "." + table.column + ", ? FROM " + table.name + " JOIN " + table.owners +
+						" ON " + table.owners + ".id = " + table.name + "." + table.column +
+						" WHERE " + table.name + ".tag_id = ? AND " + table.owners + ".user_id = ? ON CONFLICT DO NOTHING",
+						owned.ID, tag.ID, userID,
+					).Error; err != nil {
+						return err
+					}
+
+					if err := recountTags(tx, []uuid.UUID{owned.ID}); err != nil {
+						return err
+					}
+				}
+
+				if err := tx.Exec("DELETE FROM " + table.name + " WHERE tag_id = ?", tag.ID).Error; err != nil {
+					return err
+				}
+			}
+
+			if err := tx.Delete(tag).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+const tagPathSeparator = "/"
+
+// normalizeName lowercases a tag and joins the words of every path segment with dashes,
+// so "CS / Machine Learning" becomes "cs/machine-learning".
 func normalizeName(name string) string {
-	return strings.ToLower(strings.TrimSpace(strings.Replace(name, " ", "-", -1)))
+	var segments []string
+	for _, segment := range strings.Split(name, tagPathSeparator) {
+		segment = strings.ToLower(strings.Join(strings.Fields(segment), "-"))
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return strings.Join(segments, tagPathSeparator)
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
+}
+
+// tagSubtreeCondition matches a tag and every tag nested under it.
+func tagSubtreeCondition(column, name string) (string, string, string) {
+	name = normalizeName(name)
+	return "(" + column + " = ? OR " + column + " LIKE ? ESCAPE '\\')", name, escapeLike(name) + tagPathSeparator + "%"
+}
+
+func normalizeNames(names []string) []string {
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		normalized = append(normalized, normalizeName(name))
+	}
+	return normalized
 }
 
 func ExtractTagIDs(tags []model.Tag) []uuid.UUID {
@@ -256,4 +748,4 @@ func ExtractTagIDs(tags []model.Tag) []uuid.UUID {
         ids[i] = tag.ID
     }
     return ids
-}
\ No newline at end of file
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+	"github.com/box1bs/TelegraphicVault/pkg/templates"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (p *Postgres) CreateTemplate(ctx context.Context, template *model.NoteTemplate) error {
+	if err := templates.Validate(template); err != nil {
+		return err
+	}
+
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := templateNameTaken(tx, template); err != nil {
+			return err
+		}
+		return tx.Create(template).Error
+	})
+}
+
+func (p *Postgres) ListTemplates(ctx context.Context, userID uuid.UUID) ([]*model.NoteTemplate, error) {
+	noteTemplates := []*model.NoteTemplate{}
+	if err := p.db.WithContext(ctx).Where("user_id = ?", userID).Order("name").Find(&noteTemplates).Error; err != nil {
+		return nil, err
+	}
+	return noteTemplates, nil
+}
+
+func (p *Postgres) GetTemplate(ctx context.Context, userID, id uuid.UUID) (*model.NoteTemplate, error) {
+	var template model.NoteTemplate
+	if err := p.db.WithContext(ctx).Where("user_id = ? AND id = ?", userID, id).First(&template).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (p *Postgres) UpdateTemplate(ctx context.Context, template *model.NoteTemplate) (*model.NoteTemplate, error) {
+	if err := templates.Validate(template); err != nil {
+		return nil, err
+	}
+
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing model.NoteTemplate
+		if err := tx.Where("user_id = ? AND id = ?", template.UserID, template.ID).First(&existing).Error; err != nil {
+			return err
+		}
+		if err := templateNameTaken(tx, template); err != nil {
+			return err
+		}
+
+		template.CreatedAt = existing.CreatedAt
+		return tx.Save(template).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+func (p *Postgres) DeleteTemplate(ctx context.Context, userID, id uuid.UUID) error {
+	result := p.db.WithContext(ctx).Where("user_id = ? AND id = ?", userID, id).Delete(&model.NoteTemplate{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func templateNameTaken(tx *gorm.DB, template *model.NoteTemplate) error {
+	var taken int64
+	if err := tx.Model(&model.NoteTemplate{}).
+		Where("user_id = ? AND name = ? AND id <> ?", template.UserID, template.Name, template.ID).
+		Count(&taken).Error; err != nil {
+		return err
+	}
+
+	if taken > 0 {
+		return model.ErrAlreadyExists
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Trash struct {
+	Notes     []*model.Note     `json:"notes"`
+	Bookmarks []*model.Bookmark `json:"bookmarks"`
+}
+
+// ListTrash returns the deleted notes and bookmarks of the user, most recently deleted first.
+func (p *Postgres) ListTrash(ctx context.Context, userID uuid.UUID) (*Trash, error) {
+	trash := &Trash{Notes: []*model.Note{}, Bookmarks: []*model.Bookmark{}}
+	if err := p.db.WithContext(ctx).Unscoped().Preload("Tags").
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+		Order("deleted_at DESC").
+		Find(&trash.Notes).Error; err != nil {
+		return nil, err
+	}
+
+	if err := p.db.WithContext(ctx).Unscoped().Preload("Tags").
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+		Order("deleted_at DESC").
+		Find(&trash.Bookmarks).Error; err != nil {
+		return nil, err
+	}
+
+	return trash, nil
+}
+
+// RestoreNote takes a note out of the trash. A note with the same title created in the meantime
+// blocks the restore; a collection that no longer exists is dropped.
+func (p *Postgres) RestoreNote(ctx context.Context, userID, id uuid.UUID) (*model.Note, error) {
+	var note model.Note
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Preload("Tags").
+			Where("user_id = ? AND id = ? AND deleted_at IS NOT NULL", userID, id).
+			First(&note).Error; err != nil {
+			return err
+		}
+
+		var exist model.Note
+		if err := tx.Where("user_id = ? AND title = ?", userID, note.Title).First(&exist).Error; err == nil {
+			return model.ErrAlreadyExists
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if note.Type == model.NoteTypeJournal && note.JournalDate != nil {
+			// the day got a new journal note while this one was in the trash
+			if err := tx.Where("user_id = ? AND type = ? AND journal_date = ?", userID, model.NoteTypeJournal, *note.JournalDate).
+				First(&exist).Error; err == nil {
+				return model.ErrAlreadyExists
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+
+		columns := map[string]any{"deleted_at": nil}
+		if err := restoredCollection(tx, userID, note.CollectionID, columns); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&note).UpdateColumns(columns).Error; err != nil {
+			return err
+		}
+		if err := resolveIncomingLinks(tx, &note); err != nil {
+			return err
+		}
+
+		return recountTags(tx, ExtractTagIDs(note.Tags))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p.GetNoteByID(ctx, userID, id)
+}
+
+// RestoreBookmark takes a bookmark out of the trash unless the same URL was bookmarked again in the meantime.
+func (p *Postgres) RestoreBookmark(ctx context.Context, userID, id uuid.UUID) (*model.Bookmark, error) {
+	var bookmark model.Bookmark
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Preload("Tags").
+			Where("user_id = ? AND id = ? AND deleted_at IS NOT NULL", userID, id).
+			First(&bookmark).Error; err != nil {
+			return err
+		}
+
+		var exist model.Bookmark
+		if err := tx.Where("user_id = ? AND (url = ? OR normalized_url = ?)", userID, bookmark.URL, bookmark.NormalizedURL).
+			First(&exist).Error; err == nil {
+			return model.ErrAlreadyExists
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		columns := map[string]any{"deleted_at": nil}
+		if err := restoredCollection(tx, userID, bookmark.CollectionID, columns); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&bookmark).UpdateColumns(columns).Error; err != nil {
+			return err
+		}
+
+		return recountTags(tx, ExtractTagIDs(bookmark.Tags))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p.GetBookmarkByID(ctx, userID, id)
+}
+
+// restoredCollection clears the collection of a restored item when it was deleted while the item was in the trash.
+func restoredCollection(tx *gorm.DB, userID uuid.UUID, collectionID *uuid.UUID, columns map[string]any) error {
+	if collectionID == nil {
+		return nil
+	}
+
+	var count int64
+	if err := tx.Model(&model.Collection{}).Where("user_id = ? AND id = ?", userID, *collectionID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		columns["collection_id"] = nil
+	}
+
+	return nil
+}
+
+// PurgeNote permanently deletes a note, its revisions and outgoing links from the trash.
+func (p *Postgres) PurgeNote(ctx context.Context, userID, id uuid.UUID) error {
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ids, err := trashedIDs(tx.Model(&model.Note{}).Where("user_id = ? AND id = ?", userID, id))
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return purgeNotes(tx, ids)
+	})
+}
+
+// PurgeBookmark permanently deletes a bookmark and its archive from the trash.
+func (p *Postgres) PurgeBookmark(ctx context.Context, userID, id uuid.UUID) error {
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ids, err := trashedIDs(tx.Model(&model.Bookmark{}).Where("user_id = ? AND id = ?", userID, id))
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return purgeBookmarks(tx, ids)
+	})
+}
+
+// EmptyTrash permanently deletes everything in the trash of the user.
+func (p *Postgres) EmptyTrash(ctx context.Context, userID uuid.UUID) error {
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return purgeTrash(tx, func(items any) *gorm.DB {
+			return tx.Model(items).Where("user_id = ?", userID)
+		})
+	})
+}
+
+// PurgeTrash permanently deletes the items of all users that were moved to the trash before deletedBefore.
+func (p *Postgres) PurgeTrash(ctx context.Context, deletedBefore time.Time) error {
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return purgeTrash(tx, func(items any) *gorm.DB {
+			return tx.Model(items).Where("deleted_at < ?", deletedBefore)
+		})
+	})
+}
+
+func purgeTrash(tx *gorm.DB, scope func(items any) *gorm.DB) error {
+	noteIDs, err := trashedIDs(scope(&model.Note{}))
+	if err != nil {
+		return err
+	}
+	if err := purgeNotes(tx, noteIDs); err != nil {
+		return err
+	}
+
+	bookmarkIDs, err := trashedIDs(scope(&model.Bookmark{}))
+	if err != nil {
+		return err
+	}
+	return purgeBookmarks(tx, bookmarkIDs)
+}
+
+func trashedIDs(query *gorm.DB) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := query.Unscoped().Where("deleted_at IS NOT NULL").Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// trashed items are not counted in tag usage, so purging them leaves the counters unchanged
+func purgeNotes(tx *gorm.DB, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := tx.Exec("DELETE FROM note_tags WHERE note_id IN ?", ids).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("note_id IN ?", ids).Delete(&model.NoteRevision{}).Error; err != nil {
+		return err
+	}
+	// the blobs left without attachments are removed by the blob sweeper
+	if err := tx.Where("note_id IN ?", ids).Delete(&model.Attachment{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("source_id IN ?", ids).Delete(&model.NoteLink{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&model.NoteLink{}).Where("target_id IN ?", ids).UpdateColumn("target_id", nil).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Where("id IN ?", ids).Delete(&model.Note{}).Error
+}
+
+func purgeBookmarks(tx *gorm.DB, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := tx.Exec("DELETE FROM bookmark_tags WHERE bookmark_id IN ?", ids).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("bookmark_id IN ?", ids).Delete(&model.BookmarkArchive{}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Where("id IN ?", ids).Delete(&model.Bookmark{}).Error
+}
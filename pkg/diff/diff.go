@@ -0,0 +1,274 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	opEqual  = ' '
+	opDelete = '-'
+	opInsert = '+'
+)
+
+type edit struct {
+	op   byte
+	line string
+}
+
+// Unified returns a line-level unified diff of a and b with the given number of context lines,
+// or an empty string when the texts are equal.
+func Unified(fromName, toName, a, b string, context int) string {
+	edits := lineEdits(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	for _, h := range hunks(edits, context) {
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		out.WriteString(h)
+	}
+
+	return out.String()
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// lineEdits finds a shortest edit script with the linear-space variant of the Myers algorithm,
+// so memory stays proportional to the number of lines however different the texts are.
+func lineEdits(a, b []string) []edit {
+	ids := make(map[string]int)
+	intern := func(lines []string) []int {
+		out := make([]int, len(lines))
+		for i, line := range lines {
+			id, ok := ids[line]
+			if !ok {
+				id = len(ids)
+				ids[line] = id
+			}
+			out[i] = id
+		}
+		return out
+	}
+
+	d := &differ{a: a, b: b, x: intern(a), y: intern(b)}
+	d.compare(0, len(a), 0, len(b))
+	return d.edits
+}
+
+type differ struct {
+	a, b  []string
+	x, y  []int
+	edits []edit
+}
+
+// compare appends the edits turning a[aLo:aHi] into b[bLo:bHi].
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.x[aLo] == d.y[bLo] {
+		d.edits = append(d.edits, edit{opEqual, d.a[aLo]})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.x[aHi-suffix-1] == d.y[bHi-suffix-1] {
+		suffix++
+	}
+	aHi -= suffix
+	bHi -= suffix
+
+	switch {
+	case aLo == aHi:
+		for _, line := range d.b[bLo:bHi] {
+			d.edits = append(d.edits, edit{opInsert, line})
+		}
+	case bLo == bHi:
+		for _, line := range d.a[aLo:aHi] {
+			d.edits = append(d.edits, edit{opDelete, line})
+		}
+	default:
+		if x, y, ok := d.middle(aLo, aHi, bLo, bHi); ok {
+			d.compare(aLo, aLo+x, bLo, bLo+y)
+			d.compare(aLo+x, aHi, bLo+y, bHi)
+		} else {
+			for _, line := range d.a[aLo:aHi] {
+				d.edits = append(d.edits, edit{opDelete, line})
+			}
+			for _, line := range d.b[bLo:bHi] {
+				d.edits = append(d.edits, edit{opInsert, line})
+			}
+		}
+	}
+
+	for i := aHi; i < aHi+suffix; i++ {
+		d.edits = append(d.edits, edit{opEqual, d.a[i]})
+	}
+}
+
+// middle searches forward and backward at once for the point where the two halves of a shortest
+// edit path meet, returned relative to aLo and bLo.
+func (d *differ) middle(aLo, aHi, bLo, bHi int) (int, int, bool) {
+	n, m := aHi-aLo, bHi-bLo
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	size := 2*maxD + 2
+
+	forward := make([]int, size)
+	backward := make([]int, size)
+	for i := range forward {
+		forward[i] = -1
+		backward[i] = -1
+	}
+	forward[offset+1] = 0
+	backward[offset+1] = 0
+
+	delta := n - m
+	odd := delta%2 != 0
+	var fStart, fEnd, bStart, bEnd int
+
+	for step := 0; step < maxD; step++ {
+		for k := -step + fStart; k <= step-fEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -step || (k != step && forward[i-1] < forward[i+1]) {
+				x = forward[i+1]
+			} else {
+				x = forward[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.x[aLo+x] == d.y[bLo+y] {
+				x++
+				y++
+			}
+			forward[i] = x
+
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case odd:
+				j := offset + delta - k
+				if j >= 0 && j < size && backward[j] != -1 && x >= n-backward[j] {
+					return x, y, true
+				}
+			}
+		}
+
+		for k := -step + bStart; k <= step-bEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -step || (k != step && backward[i-1] < backward[i+1]) {
+				x = backward[i+1]
+			} else {
+				x = backward[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.x[aHi-x-1] == d.y[bHi-y-1] {
+				x++
+				y++
+			}
+			backward[i] = x
+
+			switch {
+			case x > n:
+				bEnd += 2
+			case y > m:
+				bStart += 2
+			case !odd:
+				j := offset + delta - k
+				if j >= 0 && j < size && forward[j] != -1 {
+					fx := forward[j]
+					fy := offset + fx - j
+					if fx >= n-x {
+						return fx, fy, true
+					}
+				}
+			}
+		}
+	}
+
+	return 0, 0, false
+}
+
+// hunks groups changes that are at most 2*context lines apart, each with surrounding context.
+func hunks(edits []edit, context int) []string {
+	var result []string
+
+	for i := 0; i < len(edits); {
+		if edits[i].op == opEqual {
+			i++
+			continue
+		}
+
+		start := max(i-context, 0)
+		end := i
+		for end < len(edits) {
+			if edits[end].op != opEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(edits) && edits[run].op == opEqual {
+				run++
+			}
+			if run == len(edits) || run-end > 2*context {
+				end = min(end+context, len(edits))
+				break
+			}
+			end = run
+		}
+
+		result = append(result, formatHunk(edits, start, end))
+		i = end
+	}
+
+	return result
+}
+
+func formatHunk(edits []edit, start, end int) string {
+	fromLine, toLine := 1, 1
+	for _, e := range edits[:start] {
+		if e.op != opInsert {
+			fromLine++
+		}
+		if e.op != opDelete {
+			toLine++
+		}
+	}
+
+	var body strings.Builder
+	fromCount, toCount := 0, 0
+	for _, e := range edits[start:end] {
+		if e.op != opInsert {
+			fromCount++
+		}
+		if e.op != opDelete {
+			toCount++
+		}
+		body.WriteByte(e.op)
+		body.WriteString(e.line)
+		body.WriteByte('\n')
+	}
+
+	// an empty side of a hunk starts at the line before it
+	if fromCount == 0 {
+		fromLine--
+	}
+	if toCount == 0 {
+		toLine--
+	}
+
+	return fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(fromLine, fromCount), hunkRange(toLine, toCount)) + body.String()
+}
+
+func hunkRange(line, count int) string {
+	if count == 1 {
+		return fmt.Sprint(line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
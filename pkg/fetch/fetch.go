@@ -0,0 +1,181 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/box1bs/TelegraphicVault/pkg/config"
+)
+
+var (
+	ErrUnsupportedScheme = errors.New("only http and https urls can be fetched")
+	ErrForbiddenAddress  = errors.New("address is not publicly routable")
+	ErrTooManyRedirects  = errors.New("too many redirects")
+)
+
+// non-public ranges that net.IP helpers do not cover
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+type Response struct {
+	URL         string
+	StatusCode  int
+	ContentType string
+	Header      http.Header
+	Body        []byte
+	Truncated   bool
+}
+
+// Fetcher downloads user-supplied URLs with bounded time, size and redirects.
+// Unless the config allows private networks, connections to loopback, private,
+// link-local and other non-public addresses outside the allowed networks are
+// refused after DNS resolution, including those reached through redirects.
+type Fetcher struct {
+	client *http.Client
+	config config.FetchConfig
+}
+
+func New(conf config.FetchConfig) *Fetcher {
+	dialer := &net.Dialer{Timeout: conf.Timeout}
+	if !conf.AllowPrivateNetworks {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil || !(isPublic(addr) || isAllowed(addr, conf.AllowedNetworks)) {
+				return ErrForbiddenAddress
+			}
+			return nil
+		}
+	}
+
+	transport := &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   conf.Timeout,
+		ResponseHeaderTimeout: conf.Timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	return &Fetcher{
+		config: conf,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   conf.Timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > conf.MaxRedirects {
+					return ErrTooManyRedirects
+				}
+				return checkScheme(req.URL)
+			},
+		},
+	}
+}
+
+// Do sends req and reads at most MaxBodySize bytes of the response body.
+func (f *Fetcher) Do(req *http.Request) (*Response, error) {
+	if err := checkScheme(req.URL); err != nil {
+		return nil, err
+	}
+
+	if f.config.UserAgent != "" {
+		req.Header.Set("User-Agent", f.config.UserAgent)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.config.MaxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Response{
+		URL:        resp.Request.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+	}
+
+	if int64(len(body)) > f.config.MaxBodySize {
+		result.Body = body[:f.config.MaxBodySize]
+		result.Truncated = true
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		result.ContentType = mediaType
+	}
+
+	return result, nil
+}
+
+func (f *Fetcher) Get(ctx context.Context, rawURL string) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return f.Do(req)
+}
+
+func (f *Fetcher) Head(ctx context.Context, rawURL string) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return f.Do(req)
+}
+
+func checkScheme(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: %q", ErrUnsupportedScheme, u.Scheme)
+	}
+	return nil
+}
+
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isAllowed(addr netip.Addr, networks []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, network := range networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
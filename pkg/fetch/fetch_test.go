@@ -0,0 +1,184 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/box1bs/TelegraphicVault/pkg/config"
+)
+
+var loopback = []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")}
+
+func testConfig(allowed []netip.Prefix) config.FetchConfig {
+	return config.FetchConfig{
+		Timeout:         5 * time.Second,
+		MaxBodySize:     1 << 10,
+		MaxRedirects:    3,
+		AllowedNetworks: allowed,
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		addr   string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"fe80::1", false},
+		{"fc00::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"224.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		if got := isPublic(netip.MustParseAddr(tt.addr)); got != tt.public {
+			t.Errorf("isPublic(%s) = %v, want %v", tt.addr, got, tt.public)
+		}
+	}
+}
+
+func TestGetRefusesPrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached a loopback server")
+	}))
+	defer server.Close()
+
+	_, err := New(testConfig(nil)).Get(context.Background(), server.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("err = %v, want %v", err, ErrForbiddenAddress)
+	}
+}
+
+func TestGetAllowedNetwork(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, "<title>ok</title>")
+	}))
+	defer server.Close()
+
+	resp, err := New(testConfig(loopback)).Get(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.ContentType != "text/html" || string(resp.Body) != "<title>ok</title>" {
+		t.Fatalf("unexpected response: %d %q %q", resp.StatusCode, resp.ContentType, resp.Body)
+	}
+}
+
+func TestGetRefusesRedirectToPrivateAddress(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.2:0")
+	if err != nil {
+		t.Skipf("no second loopback address: %v", err)
+	}
+	internal := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("redirect reached an address outside the allowed networks")
+	}))
+	internal.Listener.Close()
+	internal.Listener = listener
+	internal.Start()
+	defer internal.Close()
+
+	server := httptest.NewServer(http.RedirectHandler(internal.URL, http.StatusFound))
+	defer server.Close()
+
+	_, err = New(testConfig(loopback)).Get(context.Background(), server.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("err = %v, want %v", err, ErrForbiddenAddress)
+	}
+}
+
+// redirectChain redirects /n to /n-1 and answers /0.
+func redirectChain(w http.ResponseWriter, r *http.Request) {
+	n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+	if n > 0 {
+		http.Redirect(w, r, "/"+strconv.Itoa(n-1), http.StatusFound)
+		return
+	}
+	fmt.Fprint(w, "done")
+}
+
+func TestGetRedirectLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(redirectChain))
+	defer server.Close()
+
+	fetcher := New(testConfig(loopback))
+
+	resp, err := fetcher.Get(context.Background(), server.URL+"/3")
+	if err != nil {
+		t.Fatalf("3 redirects: %v", err)
+	}
+	if resp.URL != server.URL+"/0" || string(resp.Body) != "done" {
+		t.Fatalf("unexpected response: %s %q", resp.URL, resp.Body)
+	}
+
+	if _, err := fetcher.Get(context.Background(), server.URL+"/4"); !errors.Is(err, ErrTooManyRedirects) {
+		t.Fatalf("4 redirects: err = %v, want %v", err, ErrTooManyRedirects)
+	}
+}
+
+func TestGetRefusesRedirectToUnsupportedScheme(t *testing.T) {
+	server := httptest.NewServer(http.RedirectHandler("ftp://example.com/file", http.StatusFound))
+	defer server.Close()
+
+	_, err := New(testConfig(loopback)).Get(context.Background(), server.URL)
+	if !errors.Is(err, ErrUnsupportedScheme) {
+		t.Fatalf("err = %v, want %v", err, ErrUnsupportedScheme)
+	}
+}
+
+func TestGetBodyLimit(t *testing.T) {
+	conf := testConfig(loopback)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+		w.Write([]byte(strings.Repeat("a", size)))
+	}))
+	defer server.Close()
+
+	fetcher := New(conf)
+	tests := []struct {
+		size      int64
+		length    int64
+		truncated bool
+	}{
+		{conf.MaxBodySize - 1, conf.MaxBodySize - 1, false},
+		{conf.MaxBodySize, conf.MaxBodySize, false},
+		{conf.MaxBodySize + 1, conf.MaxBodySize, true},
+		{conf.MaxBodySize * 100, conf.MaxBodySize, true},
+	}
+
+	for _, tt := range tests {
+		resp, err := fetcher.Get(context.Background(), fmt.Sprintf("%s/?size=%d", server.URL, tt.size))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int64(len(resp.Body)) != tt.length || resp.Truncated != tt.truncated {
+			t.Errorf("size %d: got %d bytes, truncated %v; want %d bytes, truncated %v",
+				tt.size, len(resp.Body), resp.Truncated, tt.length, tt.truncated)
+		}
+	}
+}
+
+func TestGetUnsupportedScheme(t *testing.T) {
+	_, err := New(testConfig(nil)).Get(context.Background(), "file:///etc/passwd")
+	if !errors.Is(err, ErrUnsupportedScheme) {
+		t.Fatalf("err = %v, want %v", err, ErrUnsupportedScheme)
+	}
+}
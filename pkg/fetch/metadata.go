@@ -0,0 +1,160 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+type Metadata struct {
+	Title        string
+	Description  string
+	CanonicalURL string
+	FaviconURL   string
+	ContentType  string
+}
+
+// Metadata fetches rawURL and extracts its metadata with ExtractMetadata.
+func (f *Fetcher) Metadata(ctx context.Context, rawURL string) (*Metadata, error) {
+	resp, err := f.Get(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc *html.Node
+	if IsHTML(resp) {
+		if doc, err = ParseHTML(resp); err != nil {
+			return nil, err
+		}
+	}
+
+	return ExtractMetadata(resp, doc), nil
+}
+
+func IsHTML(resp *Response) bool {
+	return resp.ContentType == "text/html" || resp.ContentType == "application/xhtml+xml"
+}
+
+// ExtractMetadata reads the title, description, canonical URL and favicon of a page from
+// OpenGraph, Twitter and standard meta tags, preferring OpenGraph. doc is nil for non-html responses.
+func ExtractMetadata(resp *Response, doc *html.Node) *Metadata {
+	meta := &Metadata{CanonicalURL: resp.URL, ContentType: resp.ContentType}
+	if doc == nil {
+		return meta
+	}
+
+	base, _ := url.Parse(resp.URL)
+	properties := make(map[string]string)
+	var title, canonical, favicon string
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "title":
+				if title == "" {
+					title = textContent(n)
+				}
+			case "meta":
+				key := strings.ToLower(attr(n, "property"))
+				if key == "" {
+					key = strings.ToLower(attr(n, "name"))
+				}
+				if _, seen := properties[key]; key != "" && !seen {
+					properties[key] = strings.TrimSpace(attr(n, "content"))
+				}
+			case "link":
+				for _, rel := range strings.Fields(strings.ToLower(attr(n, "rel"))) {
+					switch rel {
+					case "canonical":
+						if canonical == "" {
+							canonical = attr(n, "href")
+						}
+					case "icon", "apple-touch-icon":
+						if favicon == "" {
+							favicon = attr(n, "href")
+						}
+					}
+				}
+			case "body":
+				if title != "" {
+					return
+				}
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	meta.Title = firstNonEmpty(properties["og:title"], properties["twitter:title"], strings.TrimSpace(title))
+	meta.Description = firstNonEmpty(properties["og:description"], properties["description"], properties["twitter:description"])
+
+	if resolved := resolve(base, firstNonEmpty(canonical, properties["og:url"])); resolved != "" {
+		meta.CanonicalURL = resolved
+	}
+	meta.FaviconURL = resolve(base, firstNonEmpty(favicon, "/favicon.ico"))
+
+	return meta
+}
+
+// ParseHTML parses the body of resp, decoding it from the charset declared in headers or markup.
+func ParseHTML(resp *Response) (*html.Node, error) {
+	reader, err := charset.NewReader(bytes.NewReader(resp.Body), resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	return html.Parse(reader)
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+func resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || base == nil {
+		return ""
+	}
+
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,54 @@
+package graph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "")
+
+func dotQuote(s string) string {
+	return `"` + dotEscaper.Replace(s) + `"`
+}
+
+// WriteDOT encodes g in the Graphviz DOT language with label, kind and weight attributes.
+func WriteDOT(w io.Writer, g *Graph, name string) error {
+	bw := bufio.NewWriter(w)
+
+	kind, arrow := "graph", "--"
+	if g.Directed {
+		kind, arrow = "digraph", "->"
+	}
+	fmt.Fprintf(bw, "%s %s {\n", kind, dotQuote(name))
+
+	for _, n := range g.Nodes {
+		attrs := []string{"label=" + dotQuote(n.Label)}
+		if n.Kind != "" {
+			attrs = append(attrs, "kind="+dotQuote(n.Kind))
+		}
+		if n.Weight != 0 {
+			attrs = append(attrs, fmt.Sprintf("weight=%d", n.Weight))
+		}
+		fmt.Fprintf(bw, "  %s [%s];\n", dotQuote(n.ID), strings.Join(attrs, ", "))
+	}
+
+	for _, e := range g.Edges {
+		var attrs []string
+		if e.Kind != "" {
+			attrs = append(attrs, "kind="+dotQuote(e.Kind))
+		}
+		if e.Weight != 0 {
+			attrs = append(attrs, fmt.Sprintf("weight=%d", e.Weight))
+		}
+		fmt.Fprintf(bw, "  %s %s %s", dotQuote(e.Source), arrow, dotQuote(e.Target))
+		if len(attrs) > 0 {
+			fmt.Fprintf(bw, " [%s]", strings.Join(attrs, ", "))
+		}
+		bw.WriteString(";\n")
+	}
+
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
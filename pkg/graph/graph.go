@@ -0,0 +1,156 @@
+package graph
+
+import (
+	"encoding/xml"
+	"io"
+	"slices"
+	"strconv"
+)
+
+type Node struct {
+	ID     string `json:"id"`
+	Label  string `json:"label"`
+	Kind   string `json:"kind,omitempty"`
+	Weight int64  `json:"weight,omitempty"`
+}
+
+type Edge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Kind   string `json:"kind,omitempty"`
+	Weight int64  `json:"weight,omitempty"`
+}
+
+type Graph struct {
+	Directed bool   `json:"directed"`
+	Nodes    []Node `json:"nodes"`
+	Edges    []Edge `json:"edges"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   struct {
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+// WriteGraphML encodes g as GraphML with label, kind and weight attributes.
+func WriteGraphML(w io.Writer, g *Graph) error {
+	doc := graphMLDocument{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "label", For: "node", AttrName: "label", AttrType: "string"},
+			{ID: "node_kind", For: "node", AttrName: "kind", AttrType: "string"},
+			{ID: "node_weight", For: "node", AttrName: "weight", AttrType: "long"},
+			{ID: "edge_kind", For: "edge", AttrName: "kind", AttrType: "string"},
+			{ID: "edge_weight", For: "edge", AttrName: "weight", AttrType: "long"},
+		},
+	}
+
+	doc.Graph.EdgeDefault = "undirected"
+	if g.Directed {
+		doc.Graph.EdgeDefault = "directed"
+	}
+
+	for _, n := range g.Nodes {
+		node := graphMLNode{ID: n.ID, Data: []graphMLData{{Key: "label", Value: n.Label}}}
+		if n.Kind != "" {
+			node.Data = append(node.Data, graphMLData{Key: "node_kind", Value: n.Kind})
+		}
+		if n.Weight != 0 {
+			node.Data = append(node.Data, graphMLData{Key: "node_weight", Value: strconv.FormatInt(n.Weight, 10)})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, node)
+	}
+
+	for _, e := range g.Edges {
+		edge := graphMLEdge{Source: e.Source, Target: e.Target}
+		if e.Kind != "" {
+			edge.Data = append(edge.Data, graphMLData{Key: "edge_kind", Value: e.Kind})
+		}
+		if e.Weight != 0 {
+			edge.Data = append(edge.Data, graphMLData{Key: "edge_weight", Value: strconv.FormatInt(e.Weight, 10)})
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, edge)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+// Neighbourhood returns the part of g within depth edges of the focus node, following edges
+// in both directions, or nil when focus is not in g.
+func (g *Graph) Neighbourhood(focus string, depth int) *Graph {
+	if !slices.ContainsFunc(g.Nodes, func(n Node) bool { return n.ID == focus }) {
+		return nil
+	}
+
+	adjacent := make(map[string][]string)
+	for _, e := range g.Edges {
+		adjacent[e.Source] = append(adjacent[e.Source], e.Target)
+		adjacent[e.Target] = append(adjacent[e.Target], e.Source)
+	}
+
+	reached := map[string]bool{focus: true}
+	frontier := []string{focus}
+	for d := 0; d < depth && len(frontier) > 0; d++ {
+		var next []string
+		for _, id := range frontier {
+			for _, other := range adjacent[id] {
+				if !reached[other] {
+					reached[other] = true
+					next = append(next, other)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	sub := &Graph{Directed: g.Directed, Nodes: []Node{}, Edges: []Edge{}}
+	for _, n := range g.Nodes {
+		if reached[n.ID] {
+			sub.Nodes = append(sub.Nodes, n)
+		}
+	}
+	for _, e := range g.Edges {
+		if reached[e.Source] && reached[e.Target] {
+			sub.Edges = append(sub.Edges, e)
+		}
+	}
+	return sub
+}
@@ -0,0 +1,25 @@
+package importer
+
+import (
+	"io"
+	"time"
+)
+
+// Entry is a bookmark read from an export file.
+type Entry struct {
+	URL         string
+	Title       string
+	Description string
+	Tags        []string
+	AddedAt     time.Time
+	// Read is nil when the format has no read status
+	Read *bool
+}
+
+// Parsers maps the formats accepted by the import endpoint to their parsers.
+var Parsers = map[string]func(io.Reader) ([]Entry, error){
+	"netscape": ParseNetscape,
+	"pocket":   ParsePocket,
+	"raindrop": ParseRaindrop,
+	"pinboard": ParsePinboard,
+}
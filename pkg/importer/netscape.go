@@ -0,0 +1,273 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	htmlparse "golang.org/x/net/html"
+)
+
+var ErrNotNetscape = errors.New("not a netscape bookmark file")
+
+// root folders of browser exports that carry no meaning as tags
+var rootFolderAttrs = []string{"personal_toolbar_folder", "unfiled_bookmarks_folder"}
+
+// ParseNetscape reads a Netscape bookmark file as exported by browsers. The path of folders
+// around a bookmark becomes one hierarchical tag, e.g. "Dev/Go", in addition to its TAGS.
+func ParseNetscape(r io.Reader) ([]Entry, error) {
+	z := htmlparse.NewTokenizer(r)
+
+	var entries []Entry
+	var folders []string
+	var pendingFolder *string
+	var current *Entry
+	seenList := false
+	var tt htmlparse.TokenType
+	pending := false
+
+	for {
+		if !pending {
+			tt = z.Next()
+		}
+		pending = false
+
+		switch tt {
+		case htmlparse.ErrorToken:
+			if errors.Is(z.Err(), io.EOF) {
+				if !seenList {
+					return nil, ErrNotNetscape
+				}
+				return entries, nil
+			}
+			return nil, z.Err()
+
+		case htmlparse.StartTagToken:
+			name, hasAttr := z.TagName()
+			attrs := map[string]string{}
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				attrs[strings.ToLower(string(key))] = string(val)
+			}
+
+			switch string(name) {
+			case "h3":
+				folder := elementText(z, "h3")
+				for _, attr := range rootFolderAttrs {
+					if attrs[attr] == "true" {
+						folder = ""
+					}
+				}
+				pendingFolder = &folder
+				current = nil
+			case "dl":
+				seenList = true
+				folder := ""
+				if pendingFolder != nil {
+					folder = *pendingFolder
+				}
+				folders = append(folders, folder)
+				pendingFolder = nil
+				current = nil
+			case "a":
+				href := strings.TrimSpace(attrs["href"])
+				title := elementText(z, "a")
+				if href == "" || strings.HasPrefix(strings.ToLower(href), "javascript:") || strings.HasPrefix(strings.ToLower(href), "place:") {
+					current = nil
+					continue
+				}
+				entries = append(entries, Entry{
+					URL:     href,
+					Title:   title,
+					Tags:    append(splitTags(attrs["tags"], ","), folderTag(folders)...),
+					AddedAt: parseUnixTime(attrs["add_date"]),
+				})
+				current = &entries[len(entries)-1]
+			case "dd":
+				if current != nil {
+					current.Description, tt = readDescription(z)
+					current = nil
+					pending = true
+				}
+			}
+
+		case htmlparse.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "dl" && len(folders) > 0 {
+				folders = folders[:len(folders)-1]
+			}
+		}
+	}
+}
+
+// elementText collects character data up to the closing tag of the element that was just opened.
+func elementText(z *htmlparse.Tokenizer, tag string) string {
+	var sb strings.Builder
+	for {
+		switch z.Next() {
+		case htmlparse.ErrorToken:
+			return strings.Join(strings.Fields(sb.String()), " ")
+		case htmlparse.TextToken:
+			sb.Write(z.Text())
+		case htmlparse.EndTagToken:
+			if name, _ := z.TagName(); string(name) == tag {
+				return strings.Join(strings.Fields(sb.String()), " ")
+			}
+		}
+	}
+}
+
+// readDescription reads the text of a <DD>, which is never closed explicitly and ends with the next tag.
+// It returns the type of that tag, which the caller still has to process.
+func readDescription(z *htmlparse.Tokenizer) (string, htmlparse.TokenType) {
+	var sb strings.Builder
+	tt := z.Next()
+	for ; tt == htmlparse.TextToken; tt = z.Next() {
+		sb.Write(z.Text())
+	}
+	return strings.Join(strings.Fields(sb.String()), " "), tt
+}
+
+func folderTag(folders []string) []string {
+	var path []string
+	for _, folder := range folders {
+		if folder = strings.TrimSpace(strings.ReplaceAll(folder, "/", "-")); folder != "" {
+			path = append(path, folder)
+		}
+	}
+	if len(path) == 0 {
+		return nil
+	}
+	return []string{strings.Join(path, "/")}
+}
+
+func splitTags(list, sep string) []string {
+	var tags []string
+	for _, tag := range strings.Split(list, sep) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// parseUnixTime accepts seconds, milliseconds or microseconds since the epoch.
+func parseUnixTime(value string) time.Time {
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}
+	}
+	switch {
+	case n > 1e15:
+		return time.UnixMicro(n)
+	case n > 1e12:
+		return time.UnixMilli(n)
+	default:
+		return time.Unix(n, 0)
+	}
+}
+
+// WriteNetscape writes bookmarks as a Netscape bookmark file that browsers can import.
+// A bookmark with a hierarchical tag is put in the nested folders of that tag, the first one
+// by name when it has several, so "dev/go" becomes the folder Go inside Dev. The other tags
+// are kept in the TAGS attribute, which Firefox and most bookmark services read.
+func WriteNetscape(w io.Writer, bookmarks []*model.Bookmark) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprint(bw, "<!DOCTYPE NETSCAPE-Bookmark-file-1>\n"+
+		"<!-- This is an automatically generated file.\n     It will be read and overwritten.\n     DO NOT EDIT! -->\n"+
+		"<META HTTP-EQUIV=\"Content-Type\" CONTENT=\"text/html; charset=UTF-8\">\n"+
+		"<TITLE>Bookmarks</TITLE>\n<H1>Bookmarks</H1>\n<DL><p>\n")
+
+	root := &exportFolder{}
+	for _, bookmark := range bookmarks {
+		folder := root
+		path := folderPath(bookmark.Tags)
+		if path != "" {
+			for _, name := range strings.Split(path, "/") {
+				folder = folder.child(name)
+			}
+		}
+		folder.bookmarks = append(folder.bookmarks, exportBookmark{bookmark, path})
+	}
+	root.write(bw, 1)
+
+	fmt.Fprint(bw, "</DL><p>\n")
+	return bw.Flush()
+}
+
+type exportFolder struct {
+	name      string
+	folders   []*exportFolder
+	bookmarks []exportBookmark
+}
+
+type exportBookmark struct {
+	*model.Bookmark
+	folder string
+}
+
+func (f *exportFolder) child(name string) *exportFolder {
+	for _, folder := range f.folders {
+		if folder.name == name {
+			return folder
+		}
+	}
+	folder := &exportFolder{name: name}
+	f.folders = append(f.folders, folder)
+	return folder
+}
+
+func (f *exportFolder) write(bw *bufio.Writer, depth int) {
+	indent := strings.Repeat("    ", depth)
+
+	slices.SortFunc(f.folders, func(a, b *exportFolder) int { return strings.Compare(a.name, b.name) })
+	for _, folder := range f.folders {
+		fmt.Fprintf(bw, "%s<DT><H3>%s</H3>\n%s<DL><p>\n", indent, html.EscapeString(folder.name), indent)
+		folder.write(bw, depth+1)
+		fmt.Fprintf(bw, "%s</DL><p>\n", indent)
+	}
+
+	for _, bookmark := range f.bookmarks {
+		fmt.Fprintf(bw, "%s<DT><A HREF=\"%s\" ADD_DATE=\"%d\"", indent, html.EscapeString(bookmark.URL), bookmark.CreatedAt.Unix())
+
+		var names []string
+		for _, tag := range bookmark.Tags {
+			if tag.Name != bookmark.folder {
+				names = append(names, tag.Name)
+			}
+		}
+		if len(names) > 0 {
+			fmt.Fprintf(bw, " TAGS=\"%s\"", html.EscapeString(strings.Join(names, ",")))
+		}
+
+		title := bookmark.Title
+		if title == "" {
+			title = bookmark.URL
+		}
+		fmt.Fprintf(bw, ">%s</A>\n", html.EscapeString(title))
+
+		if bookmark.Description != "" {
+			fmt.Fprintf(bw, "%s<DD>%s\n", indent, html.EscapeString(bookmark.Description))
+		}
+	}
+}
+
+// folderPath picks the hierarchical tag that becomes the folder of a bookmark, or "" when it has none.
+func folderPath(tags []model.Tag) string {
+	path := ""
+	for _, tag := range tags {
+		if strings.Contains(tag.Name, "/") && (path == "" || tag.Name < path) {
+			path = tag.Name
+		}
+	}
+	return path
+}
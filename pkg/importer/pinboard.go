@@ -0,0 +1,40 @@
+package importer
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+type pinboardPost struct {
+	Href        string `json:"href"`
+	Description string `json:"description"`
+	Extended    string `json:"extended"`
+	Time        string `json:"time"`
+	Tags        string `json:"tags"`
+	ToRead      string `json:"toread"`
+}
+
+// ParsePinboard reads the json export of Pinboard, where "description" is the title,
+// "extended" the description and tags are separated by spaces.
+func ParsePinboard(r io.Reader) ([]Entry, error) {
+	var posts []pinboardPost
+	if err := json.NewDecoder(r).Decode(&posts); err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(posts))
+	for _, post := range posts {
+		isRead := post.ToRead != "yes"
+		entries = append(entries, Entry{
+			URL:         strings.TrimSpace(post.Href),
+			Title:       strings.TrimSpace(post.Description),
+			Description: strings.TrimSpace(post.Extended),
+			Tags:        strings.Fields(post.Tags),
+			AddedAt:     parseTimestamp(post.Time),
+			Read:        &isRead,
+		})
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,95 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+
+	htmlparse "golang.org/x/net/html"
+)
+
+var ErrNotPocket = errors.New("not a pocket export")
+
+// ParsePocket reads a Pocket export, either the legacy ril_export.html or the csv export.
+func ParsePocket(r io.Reader) ([]Entry, error) {
+	br := bufio.NewReader(r)
+	start, err := br.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(strings.TrimPrefix(string(start), "\ufeff")), "<") {
+		return parsePocketHTML(br)
+	}
+	return parsePocketCSV(br)
+}
+
+// parsePocketHTML reads lists of links under the "Unread" and "Read Archive" headings.
+func parsePocketHTML(r io.Reader) ([]Entry, error) {
+	z := htmlparse.NewTokenizer(r)
+
+	var entries []Entry
+	read := false
+	seenSection := false
+
+	for {
+		switch z.Next() {
+		case htmlparse.ErrorToken:
+			if errors.Is(z.Err(), io.EOF) {
+				if !seenSection {
+					return nil, ErrNotPocket
+				}
+				return entries, nil
+			}
+			return nil, z.Err()
+
+		case htmlparse.StartTagToken:
+			name, hasAttr := z.TagName()
+			attrs := map[string]string{}
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				attrs[strings.ToLower(string(key))] = string(val)
+			}
+
+			switch string(name) {
+			case "h1":
+				seenSection = true
+				read = strings.Contains(strings.ToLower(elementText(z, "h1")), "read archive")
+			case "a":
+				isRead := read
+				entries = append(entries, Entry{
+					URL:     strings.TrimSpace(attrs["href"]),
+					Title:   elementText(z, "a"),
+					Tags:    splitTags(attrs["tags"], ","),
+					AddedAt: parseUnixTime(attrs["time_added"]),
+					Read:    &isRead,
+				})
+			}
+		}
+	}
+}
+
+// parsePocketCSV reads the csv export with title, url, time_added, tags and status columns.
+// Tags are separated by "|" and status is either "unread" or "archive".
+func parsePocketCSV(r io.Reader) ([]Entry, error) {
+	rows, err := csvRows(r, "url")
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(rows))
+	for _, row := range rows {
+		isRead := row["status"] == "archive" || row["status"] == "read"
+		entries = append(entries, Entry{
+			URL:     row["url"],
+			Title:   row["title"],
+			Tags:    splitTags(row["tags"], "|"),
+			AddedAt: parseUnixTime(row["time_added"]),
+			Read:    &isRead,
+		})
+	}
+
+	return entries, nil
+}
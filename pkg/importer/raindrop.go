@@ -0,0 +1,51 @@
+package importer
+
+import (
+	"io"
+	"strings"
+	"time"
+)
+
+// ParseRaindrop reads a Raindrop.io csv export. The note, or the excerpt when there is none,
+// becomes the description and the collection becomes a tag next to the bookmark tags.
+func ParseRaindrop(r io.Reader) ([]Entry, error) {
+	rows, err := csvRows(r, "url")
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(rows))
+	for _, row := range rows {
+		tags := splitTags(row["tags"], ",")
+		if folder := strings.TrimSpace(row["folder"]); folder != "" && !strings.EqualFold(folder, "unsorted") {
+			tags = append(tags, folder)
+		}
+
+		entries = append(entries, Entry{
+			URL:         row["url"],
+			Title:       row["title"],
+			Description: firstNonEmpty(row["note"], row["excerpt"]),
+			Tags:        tags,
+			AddedAt:     parseTimestamp(row["created"]),
+		})
+	}
+
+	return entries, nil
+}
+
+// parseTimestamp accepts RFC 3339 times with or without fractional seconds and unix timestamps.
+func parseTimestamp(value string) time.Time {
+	if t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(value)); err == nil {
+		return t
+	}
+	return parseUnixTime(value)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
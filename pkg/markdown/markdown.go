@@ -0,0 +1,138 @@
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// Heading is an entry of the table of contents; ID is the anchor of the heading in the HTML.
+type Heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+	ID    string `json:"id"`
+}
+
+type Document struct {
+	HTML string    `json:"html"`
+	TOC  []Heading `json:"toc"`
+}
+
+var renderer = goldmark.New(
+	goldmark.WithExtensions(extension.GFM, extension.Footnote),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+)
+
+var policy = newPolicy()
+
+// newPolicy extends the user-generated content policy with what CommonMark and GFM output needs:
+// heading and footnote anchors, task list checkboxes and footnote classes.
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("id").Matching(regexp.MustCompile(`^[\p{L}\p{N}_:-]+$`)).OnElements("h1", "h2", "h3", "h4", "h5", "h6", "li", "sup")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^footnote(s|-ref|-backref)?$`)).OnElements("a", "div", "sup")
+	p.AllowAttrs("role").Matching(regexp.MustCompile(`^doc-(noteref|backlink|endnotes)$`)).OnElements("a", "div")
+	return p
+}
+
+// Render converts CommonMark with GFM tables, task lists, strikethrough, autolinks and footnotes
+// to sanitised HTML. Raw HTML in the source is dropped.
+func Render(source string) (*Document, error) {
+	src := []byte(source)
+	ctx := parser.NewContext(parser.WithIDs(newIDs()))
+	root := renderer.Parser().Parse(text.NewReader(src), parser.WithContext(ctx))
+
+	toc := []Heading{}
+	err := ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		id, _ := heading.AttributeString("id")
+		anchor, _ := id.([]byte)
+		toc = append(toc, Heading{Level: heading.Level, Text: plainText(heading, src), ID: string(anchor)})
+		return ast.WalkSkipChildren, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := renderer.Renderer().Render(&buf, src, root); err != nil {
+		return nil, err
+	}
+
+	return &Document{HTML: policy.Sanitize(buf.String()), TOC: toc}, nil
+}
+
+func plainText(n ast.Node, source []byte) string {
+	var b strings.Builder
+	_ = ast.Walk(n, func(child ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := child.(type) {
+		case *ast.Text:
+			b.Write(t.Segment.Value(source))
+			if t.SoftLineBreak() || t.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(t.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.TrimSpace(b.String())
+}
+
+// ids generates heading anchors that keep letters of any script, so non-Latin headings
+// get readable anchors too. Repeated headings get a numeric suffix.
+type ids struct {
+	used map[string]bool
+}
+
+func newIDs() *ids {
+	return &ids{used: make(map[string]bool)}
+}
+
+func (s *ids) Generate(value []byte, _ ast.NodeKind) []byte {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.TrimSpace(string(value)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			b.WriteRune(unicode.ToLower(r))
+			dash = false
+		case (unicode.IsSpace(r) || r == '-' || r == '_') && b.Len() > 0 && !dash:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+
+	id := strings.TrimSuffix(b.String(), "-")
+	if id == "" {
+		id = "heading"
+	}
+
+	unique := id
+	for i := 1; s.used[unique]; i++ {
+		unique = id + "-" + strconv.Itoa(i)
+	}
+	s.used[unique] = true
+	return []byte(unique)
+}
+
+func (s *ids) Put(value []byte) {
+	s.used[string(value)] = true
+}
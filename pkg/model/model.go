@@ -4,12 +4,47 @@ import (
 	"errors"
 	"time"
 
+	"github.com/box1bs/TelegraphicVault/pkg/urlnorm"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 var (
 	ErrAlreadyExists = errors.New("record already exists")
+	ErrInvalidTag    = errors.New("invalid tag")
+	ErrInvalidRule   = errors.New("invalid rule")
+	ErrQuotaExceeded = errors.New("storage quota exceeded")
+	ErrNoRedirect    = errors.New("bookmark has no redirect target")
+	ErrInvalidImport = errors.New("invalid import")
+	ErrInvalidStatus = errors.New("invalid read status")
+	ErrInvalidCollection = errors.New("invalid collection")
+	ErrInvalidPosition = errors.New("invalid position")
+	ErrInvalidSettings = errors.New("invalid settings")
+	ErrInvalidTemplate = errors.New("invalid template")
+	ErrInvalidTitle = errors.New("invalid title")
+	ErrInvalidOrder = errors.New("unsupported order")
+)
+
+const (
+	ReadStatusUnread   = "unread"
+	ReadStatusReading  = "reading"
+	ReadStatusRead     = "read"
+	ReadStatusArchived = "archived"
+)
+
+// NoteTypeJournal marks the daily journal notes; every other note has NoteTypeNote.
+const (
+	NoteTypeNote    = "note"
+	NoteTypeJournal = "journal"
+)
+
+var ReadStatuses = []string{ReadStatusUnread, ReadStatusReading, ReadStatusRead, ReadStatusArchived}
+
+const (
+	LinkHealthOK         = "ok"
+	LinkHealthRedirected = "redirected"
+	LinkHealthBroken     = "broken"
 )
 
 type Bookmark struct {
@@ -20,16 +55,55 @@ type Bookmark struct {
 	Tags       	[]Tag		`json:"tags" gorm:"many2many:bookmark_tags;constraint:OnDelete:CASCADE;"`
 	UserID     	uuid.UUID	`json:"-" gorm:"not null"`
 	CreatedAt  	time.Time	`json:"created_at" gorm:"autoCreateTime"`
+	CanonicalURL string		`json:"canonical_url,omitempty"`
+	FaviconURL 	string		`json:"favicon_url,omitempty"`
+	ContentType string		`json:"content_type,omitempty"`
+	FetchedAt 	*time.Time	`json:"fetched_at,omitempty"`
+	ArticleText string		`json:"-"`
+	ExtractedAt *time.Time	`json:"extracted_at,omitempty"`
+	Health 		string		`json:"health,omitempty" gorm:"index"`
+	StatusCode 	int			`json:"status_code,omitempty"`
+	RedirectURL string		`json:"redirect_url,omitempty"`
+	LinkFailures int		`json:"link_failures"`
+	CheckedAt 	*time.Time	`json:"checked_at,omitempty" gorm:"index"`
+	NormalizedURL string	`json:"-" gorm:"index"`
+	ReadStatus 	string		`json:"read_status" gorm:"not null;default:unread;index"`
+	ReadingMinutes int		`json:"reading_minutes,omitempty"`
+	StartedAt 	*time.Time	`json:"started_at,omitempty"`
+	ReadAt 		*time.Time	`json:"read_at,omitempty"`
+	ArchivedAt 	*time.Time	`json:"archived_at,omitempty"`
+	CollectionID *uuid.UUID	`json:"collection_id,omitempty" gorm:"index"`
+	Pinned 		bool		`json:"pinned" gorm:"not null;default:false;index"`
+	Favorite 	bool		`json:"favorite" gorm:"not null;default:false;index"`
+	PinRank 	string		`json:"pin_rank,omitempty"`
+	DeletedAt 	gorm.DeletedAt	`json:"deleted_at,omitempty" gorm:"index"`
 }
 
+// BeforeCreate rejects bookmarks whose URL differs from an existing one only in
+// letter case, scheme, trailing slash or tracking parameters.
 func (b *Bookmark) BeforeCreate(tx *gorm.DB) error {
+	b.NormalizedURL = urlnorm.Canonical(b.URL)
+
 	var exist Bookmark
-	if err := tx.Model(&Bookmark{}).Where("user_id = ? AND url = ?", b.UserID, b.URL).First(&exist).Error; err == nil {
+	if err := tx.Model(&Bookmark{}).Where("user_id = ? AND (url = ? OR normalized_url = ?)", b.UserID, b.URL, b.NormalizedURL).First(&exist).Error; err == nil {
 		return ErrAlreadyExists
 	}
 	return nil
 }
 
+// BookmarkArchive is a gzip-compressed offline snapshot of a bookmarked page.
+type BookmarkArchive struct {
+	ID 			uuid.UUID	`json:"id" gorm:"primaryKey;default:gen_random_uuid()"`
+	BookmarkID 	uuid.UUID	`json:"bookmark_id" gorm:"not null;uniqueIndex"`
+	UserID 		uuid.UUID	`json:"-" gorm:"not null;index"`
+	Mode 		string		`json:"mode"`
+	ContentType string		`json:"content_type"`
+	Size 		int64		`json:"size"`
+	StoredSize 	int64		`json:"stored_size"`
+	Data 		[]byte		`json:"-"`
+	CreatedAt 	time.Time	`json:"created_at" gorm:"autoCreateTime"`
+}
+
 type Note struct {
 	ID          	uuid.UUID	`json:"id" gorm:"primaryKey;default:gen_random_uuid()"`
 	Title       	string		`json:"title"`
@@ -37,6 +111,15 @@ type Note struct {
 	Tags        	[]Tag		`json:"tags" gorm:"many2many:note_tags;constraint:OnDelete:CASCADE;"`
 	UserID      	uuid.UUID	`json:"-"`
 	CreatedAt   	time.Time	`json:"created_at" gorm:"autoCreateTime"`
+	CollectionID 	*uuid.UUID	`json:"collection_id,omitempty" gorm:"index"`
+	Pinned 			bool		`json:"pinned" gorm:"not null;default:false;index"`
+	Favorite 		bool		`json:"favorite" gorm:"not null;default:false;index"`
+	PinRank 		string		`json:"pin_rank,omitempty"`
+	DeletedAt 		gorm.DeletedAt	`json:"deleted_at,omitempty" gorm:"index"`
+	Attachments 	[]Attachment	`json:"attachments,omitempty" gorm:"constraint:OnDelete:CASCADE;"`
+	Type 			string			`json:"type" gorm:"not null;default:note;index"`
+	// JournalDate is the day of a journal note as YYYY-MM-DD in the timezone of its owner
+	JournalDate 	*string			`json:"journal_date,omitempty" gorm:"size:10;index"`
 }
 
 func (n *Note) BeforeCreate(tx *gorm.DB) error {
@@ -47,6 +130,72 @@ func (n *Note) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// NoteLink is a [[Title]] reference from one note to another. TargetID stays empty
+// while the user has no note titled TargetTitle.
+type NoteLink struct {
+	SourceID 	uuid.UUID	`json:"source_id" gorm:"primaryKey"`
+	TargetTitle string		`json:"target_title" gorm:"primaryKey;index:idx_note_links_user_title,priority:2"`
+	TargetID 	*uuid.UUID	`json:"target_id" gorm:"index"`
+	UserID 		uuid.UUID	`json:"-" gorm:"not null;index:idx_note_links_user_title,priority:1"`
+}
+
+// NoteRevision is an immutable snapshot of a note taken after every change.
+type NoteRevision struct {
+	ID 			uuid.UUID	`json:"id" gorm:"primaryKey;default:gen_random_uuid()"`
+	NoteID 		uuid.UUID	`json:"note_id" gorm:"not null;uniqueIndex:idx_note_revisions_note_number"`
+	Number 		int			`json:"number" gorm:"not null;uniqueIndex:idx_note_revisions_note_number"`
+	Title 		string		`json:"title"`
+	Content 	string		`json:"content,omitempty"`
+	Tags 		[]string	`json:"tags" gorm:"serializer:json"`
+	AuthorID 	uuid.UUID	`json:"author_id" gorm:"not null"`
+	Author 		string		`json:"author"`
+	UserID 		uuid.UUID	`json:"-" gorm:"not null;index"`
+	CreatedAt 	time.Time	`json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TemplatePrompt is a custom placeholder of a note template, filled in when a note is created from it.
+type TemplatePrompt struct {
+	Name 		string	`json:"name"`
+	Label 		string	`json:"label,omitempty"`
+	Default 	string	`json:"default,omitempty"`
+	Required 	bool	`json:"required,omitempty"`
+}
+
+// NoteTemplate is the skeleton of a note. The {{placeholders}} in Title and Content are replaced
+// and Tags are added when a note is created from it.
+type NoteTemplate struct {
+	ID 			uuid.UUID			`json:"id" gorm:"primaryKey;default:gen_random_uuid()"`
+	Name 		string				`json:"name" gorm:"not null;uniqueIndex:idx_note_templates_user_name"`
+	Title 		string				`json:"title"`
+	Content 	string				`json:"content"`
+	Tags 		[]string			`json:"tags" gorm:"serializer:json"`
+	Prompts 	[]TemplatePrompt	`json:"prompts" gorm:"serializer:json"`
+	UserID 		uuid.UUID			`json:"-" gorm:"not null;uniqueIndex:idx_note_templates_user_name"`
+	CreatedAt 	time.Time			`json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt 	time.Time			`json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// Attachment is a file uploaded to a note. Its content is the blob with the same SHA256.
+type Attachment struct {
+	ID 			uuid.UUID	`json:"id" gorm:"primaryKey;default:gen_random_uuid()"`
+	NoteID 		uuid.UUID	`json:"note_id" gorm:"not null;index"`
+	UserID 		uuid.UUID	`json:"-" gorm:"not null;index"`
+	Filename 	string		`json:"filename" gorm:"not null"`
+	ContentType string		`json:"content_type"`
+	Size 		int64		`json:"size"`
+	SHA256 		string		`json:"sha256" gorm:"column:sha256;not null;index"`
+	CreatedAt 	time.Time	`json:"created_at" gorm:"autoCreateTime"`
+}
+
+// Blob is attachment content kept once in the blob store under its SHA-256 digest,
+// however many attachments share it.
+type Blob struct {
+	SHA256 		string		`json:"sha256" gorm:"column:sha256;primaryKey"`
+	Size 		int64		`json:"size"`
+	ContentType string		`json:"content_type"`
+	CreatedAt 	time.Time	`json:"created_at" gorm:"autoCreateTime"`
+}
+
 type User struct {
 	ID           uuid.UUID `json:"-" gorm:"primaryKey;default:gen_random_uuid()"`
     Username     string    `json:"username" gorm:"unique;not null"`
@@ -54,10 +203,72 @@ type User struct {
     Role         string    `json:"role"`
     CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
     LastLoginAt  time.Time `json:"last_login_at"`
+    // RevisionLimit and RevisionDays bound the kept note revisions; zero means no limit
+    RevisionLimit int      `json:"revision_limit" gorm:"not null;default:100"`
+    RevisionDays  int      `json:"revision_days" gorm:"not null;default:0"`
+    // Timezone is an IANA name; journal dates and template placeholders use it
+    Timezone      string   `json:"timezone" gorm:"not null;default:UTC"`
+    JournalTemplateID *uuid.UUID `json:"journal_template_id"`
 }
 
 type Tag struct {
-	ID    uuid.UUID	`json:"id,omitempty" gorm:"primaryKey;default:gen_random_uuid()"`
-	Name  string	`json:"name"`
-	Count int64		`json:"count,omitempty" gorm:"default:0"`
-}
\ No newline at end of file
+	ID    		uuid.UUID	`json:"id,omitempty" gorm:"primaryKey;default:gen_random_uuid()"`
+	Name  		string		`json:"name" gorm:"uniqueIndex:idx_tags_user_name"`
+	Count 		int64		`json:"count,omitempty" gorm:"default:0"`
+	Color 		string		`json:"color,omitempty"`
+	Description string		`json:"description,omitempty"`
+	UserID		uuid.UUID	`json:"-" gorm:"uniqueIndex:idx_tags_user_name"`
+	LastUsedAt	*time.Time	`json:"last_used_at,omitempty"`
+}
+
+// TagAlias makes Alias resolve to the tag with TagID whenever tags are written or queried.
+type TagAlias struct {
+	ID 		uuid.UUID	`json:"id" gorm:"primaryKey;default:gen_random_uuid()"`
+	Alias 	string		`json:"alias" gorm:"not null;uniqueIndex:idx_tag_aliases_user_alias"`
+	TagID 	uuid.UUID	`json:"tag_id" gorm:"not null;index"`
+	UserID 	uuid.UUID	`json:"-" gorm:"not null;uniqueIndex:idx_tag_aliases_user_alias"`
+}
+
+const (
+	RuleConditionRegex   = "regex"
+	RuleConditionDomain  = "domain"
+	RuleConditionKeyword = "keyword"
+)
+
+const (
+	RuleTargetNote     = "note"
+	RuleTargetBookmark = "bookmark"
+)
+
+// Collection is a folder of notes and bookmarks. Collections nest through ParentID
+// and are ordered among their siblings by Position.
+type Collection struct {
+	ID 			uuid.UUID	`json:"id" gorm:"primaryKey;default:gen_random_uuid()"`
+	Name 		string		`json:"name" gorm:"not null"`
+	Icon 		string		`json:"icon"`
+	ParentID 	*uuid.UUID	`json:"parent_id" gorm:"index"`
+	Position 	int			`json:"position"`
+	UserID 		uuid.UUID	`json:"-" gorm:"not null;index"`
+	CreatedAt 	time.Time	`json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TagRule tags notes and bookmarks whose Field satisfies Condition with Pattern.
+// Empty Target and Field apply the rule to both item kinds and to every text field.
+type TagRule struct {
+	ID        	uuid.UUID	`json:"id" gorm:"primaryKey;default:gen_random_uuid()"`
+	Name      	string		`json:"name"`
+	Target    	string		`json:"target"`
+	Field     	string		`json:"field"`
+	Condition 	string		`json:"condition" gorm:"not null"`
+	Pattern   	string		`json:"pattern" gorm:"not null"`
+	Tag       	string		`json:"tag" gorm:"not null"`
+	Enabled   	bool		`json:"enabled" gorm:"not null"`
+	UserID    	uuid.UUID	`json:"-" gorm:"not null;index"`
+	CreatedAt 	time.Time	`json:"created_at" gorm:"autoCreateTime"`
+}
+
+// Migration records a one-time data migration that has already run.
+type Migration struct {
+	Name 		string		`gorm:"primaryKey"`
+	AppliedAt 	time.Time	`gorm:"autoCreateTime"`
+}
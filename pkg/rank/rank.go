@@ -0,0 +1,86 @@
+package rank
+
+import (
+	"errors"
+	"strings"
+)
+
+// digits are ordered the same way bytewise and in the "C" collation
+const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+var ErrInvalidRange = errors.New("lower rank must sort before the upper rank")
+
+// Between returns a rank that sorts strictly between lower and upper, where an empty lower
+// means the start of the list and an empty upper its end. Ranks are compared bytewise, so
+// an item can always be placed between two others without renumbering the rest.
+func Between(lower, upper string) (string, error) {
+	if upper != "" && lower >= upper {
+		return "", ErrInvalidRange
+	}
+	if !valid(lower) || !valid(upper) {
+		return "", ErrInvalidRange
+	}
+	return midpoint(lower, upper), nil
+}
+
+// valid ranks use only digits and never end in the smallest digit,
+// which guarantees there is always room below them
+func valid(r string) bool {
+	if strings.HasSuffix(r, digits[:1]) {
+		return false
+	}
+	for i := 0; i < len(r); i++ {
+		if strings.IndexByte(digits, r[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func midpoint(lower, upper string) string {
+	if upper != "" {
+		// keep the common prefix and split the remainder
+		n := 0
+		for n < len(upper) && digitAt(lower, n) == upper[n] {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(lower) {
+				rest = lower[n:]
+			}
+			return upper[:n] + midpoint(rest, upper[n:])
+		}
+	}
+
+	low := 0
+	if lower != "" {
+		low = strings.IndexByte(digits, lower[0])
+	}
+	high := len(digits)
+	if upper != "" {
+		high = strings.IndexByte(digits, upper[0])
+	}
+
+	if high-low > 1 {
+		return string(digits[(low+high+1)/2])
+	}
+
+	// the first digits are adjacent: a shorter upper rank already sorts above lower,
+	// otherwise extend lower with a digit between its remainder and the end
+	if len(upper) > 1 {
+		return upper[:1]
+	}
+	rest := ""
+	if len(lower) > 1 {
+		rest = lower[1:]
+	}
+	return string(digits[low]) + midpoint(rest, "")
+}
+
+func digitAt(r string, i int) byte {
+	if i < len(r) {
+		return r[i]
+	}
+	return digits[0]
+}
@@ -0,0 +1,122 @@
+package rank
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// between fails the test unless Between returns a valid rank strictly between lower and upper.
+func between(t *testing.T, lower, upper string) string {
+	t.Helper()
+	r, err := Between(lower, upper)
+	if err != nil {
+		t.Fatalf("Between(%q, %q): %v", lower, upper, err)
+	}
+	if !valid(r) || r == "" {
+		t.Fatalf("Between(%q, %q) = %q, not a valid rank", lower, upper, r)
+	}
+	// ranks are compared bytewise, as PostgreSQL does with COLLATE "C"
+	if r <= lower || (upper != "" && r >= upper) {
+		t.Fatalf("Between(%q, %q) = %q, not strictly between", lower, upper, r)
+	}
+	return r
+}
+
+func TestBetweenEmptyBounds(t *testing.T) {
+	first := between(t, "", "")
+	between(t, "", first)
+	between(t, first, "")
+}
+
+func TestBetweenAdjacent(t *testing.T) {
+	tests := [][2]string{
+		{"a", "b"},
+		{"1", "2"},
+		{"y", "z"},
+		{"a", "a1"},
+		{"a1", "a2"},
+		{"a5", "b3"},
+		{"az", "b"},
+		{"zz", ""},
+		{"", "01"},
+		{"", "001"},
+		{"0z", "1"},
+	}
+
+	for _, tt := range tests {
+		between(t, tt[0], tt[1])
+	}
+}
+
+func TestBetweenInvalid(t *testing.T) {
+	tests := [][2]string{
+		{"b", "a"},
+		{"a", "a"},
+		{"a0", ""},
+		{"", "a0"},
+		{"A", ""},
+		{"", "a-b"},
+	}
+
+	for _, tt := range tests {
+		if _, err := Between(tt[0], tt[1]); !errors.Is(err, ErrInvalidRange) {
+			t.Errorf("Between(%q, %q): err = %v, want %v", tt[0], tt[1], err, ErrInvalidRange)
+		}
+	}
+}
+
+func TestBetweenRepeatedHeadInserts(t *testing.T) {
+	head := between(t, "", "")
+	for i := 0; i < 1000; i++ {
+		head = between(t, "", head)
+	}
+}
+
+func TestBetweenRepeatedTailInserts(t *testing.T) {
+	tail := between(t, "", "")
+	for i := 0; i < 1000; i++ {
+		tail = between(t, tail, "")
+	}
+}
+
+func TestBetweenRepeatedInsertsAtTheSameGap(t *testing.T) {
+	lower := between(t, "", "")
+	upper := between(t, lower, "")
+	for i := 0; i < 1000; i++ {
+		// alternate the side, so the gap keeps shrinking from both ends
+		if i%2 == 0 {
+			lower = between(t, lower, upper)
+		} else {
+			upper = between(t, lower, upper)
+		}
+	}
+}
+
+func TestBetweenKeepsListOrder(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	// ranks in list order; every insert goes to a random position
+	var list []string
+	for i := 0; i < 2000; i++ {
+		at := random.Intn(len(list) + 1)
+		lower, upper := "", ""
+		if at > 0 {
+			lower = list[at-1]
+		}
+		if at < len(list) {
+			upper = list[at]
+		}
+		list = slices.Insert(list, at, between(t, lower, upper))
+	}
+
+	if !slices.IsSorted(list) {
+		t.Fatal("ranks do not sort in list order")
+	}
+	sorted := slices.Clone(list)
+	slices.Sort(sorted)
+	if len(slices.Compact(sorted)) != len(list) {
+		t.Fatal("duplicate ranks")
+	}
+}
@@ -0,0 +1,179 @@
+package rules
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+)
+
+const (
+	FieldURL         = "url"
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldContent     = "content"
+)
+
+// Item is the text of a note or a bookmark that rules are evaluated against.
+type Item struct {
+	Kind        string
+	URL         string
+	Title       string
+	Description string
+	Content     string
+}
+
+func BookmarkItem(b *model.Bookmark) Item {
+	return Item{
+		Kind:        model.RuleTargetBookmark,
+		URL:         b.URL,
+		Title:       b.Title,
+		Description: b.Description,
+	}
+}
+
+func NoteItem(n *model.Note) Item {
+	return Item{
+		Kind:    model.RuleTargetNote,
+		Title:   n.Title,
+		Content: n.Content,
+	}
+}
+
+func Validate(rule *model.TagRule) error {
+	switch rule.Target {
+	case "", model.RuleTargetNote, model.RuleTargetBookmark:
+	default:
+		return model.ErrInvalidRule
+	}
+
+	switch rule.Field {
+	case "", FieldURL, FieldTitle, FieldDescription, FieldContent:
+	default:
+		return model.ErrInvalidRule
+	}
+
+	if strings.TrimSpace(rule.Pattern) == "" || strings.TrimSpace(rule.Tag) == "" {
+		return model.ErrInvalidRule
+	}
+
+	switch rule.Condition {
+	case model.RuleConditionRegex:
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return model.ErrInvalidRule
+		}
+	case model.RuleConditionDomain:
+		if rule.Field != "" && rule.Field != FieldURL {
+			return model.ErrInvalidRule
+		}
+	case model.RuleConditionKeyword:
+	default:
+		return model.ErrInvalidRule
+	}
+
+	return nil
+}
+
+// Set holds enabled rules ready to be matched against many items: regular expressions are
+// compiled once instead of on every match.
+type Set struct {
+	rules []compiled
+}
+
+type compiled struct {
+	rule *model.TagRule
+	re   *regexp.Regexp
+}
+
+// Compile prepares the enabled rules of tagRules. Regex rules with an invalid pattern never match.
+func Compile(tagRules []model.TagRule) *Set {
+	set := &Set{}
+	for i := range tagRules {
+		rule := &tagRules[i]
+		if !rule.Enabled {
+			continue
+		}
+
+		c := compiled{rule: rule}
+		if rule.Condition == model.RuleConditionRegex {
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				continue
+			}
+			c.re = re
+		}
+		set.rules = append(set.rules, c)
+	}
+	return set
+}
+
+// Apply returns the tags of every rule in the set that matches item.
+func (s *Set) Apply(item Item) []string {
+	var tags []string
+	for _, c := range s.rules {
+		if match(c.rule, c.re, item) {
+			tags = append(tags, c.rule.Tag)
+		}
+	}
+	return tags
+}
+
+// Apply returns the tags of every enabled rule that matches item.
+func Apply(tagRules []model.TagRule, item Item) []string {
+	return Compile(tagRules).Apply(item)
+}
+
+// match evaluates rule against item; re is the compiled pattern of a regex rule.
+func match(rule *model.TagRule, re *regexp.Regexp, item Item) bool {
+	if rule.Target != "" && rule.Target != item.Kind {
+		return false
+	}
+
+	switch rule.Condition {
+	case model.RuleConditionDomain:
+		return matchDomain(rule.Pattern, item.URL)
+	case model.RuleConditionRegex:
+		for _, text := range fields(rule.Field, item) {
+			if re.MatchString(text) {
+				return true
+			}
+		}
+	case model.RuleConditionKeyword:
+		keyword := strings.ToLower(rule.Pattern)
+		for _, text := range fields(rule.Field, item) {
+			if strings.Contains(strings.ToLower(text), keyword) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// matchDomain reports whether the host of rawURL is domain or one of its subdomains.
+func matchDomain(domain, rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "*."))
+	return host != "" && (host == domain || strings.HasSuffix(host, "."+domain))
+}
+
+func fields(field string, item Item) []string {
+	switch field {
+	case FieldURL:
+		return []string{item.URL}
+	case FieldTitle:
+		return []string{item.Title}
+	case FieldDescription:
+		return []string{item.Description}
+	case FieldContent:
+		return []string{item.Content}
+	default:
+		return []string{item.URL, item.Title, item.Description, item.Content}
+	}
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/box1bs/TelegraphicVault/pkg/archive"
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// archived pages are served from our origin, so nothing in them may run or reach the network
+const archiveContentSecurityPolicy = "sandbox; default-src 'none'; img-src data:; media-src data:; style-src 'unsafe-inline' data:; font-src data:"
+
+// archiveBookmark stores a snapshot of the page in the background, sharing fetch slots with page processing.
+func (s *server) archiveBookmark(userID, bookmarkID uuid.UUID, uri, mode string) {
+	go func() {
+		s.fetchSlots <- struct{}{}
+		defer func() { <-s.fetchSlots }()
+
+		snapshot, err := s.archiver.Snapshot(context.Background(), uri, mode)
+		if err != nil {
+			log.Printf("archiving %s failed: %v\n", bookmarkID, err)
+			return
+		}
+
+		data, err := archive.Compress(snapshot.Content)
+		if err != nil {
+			log.Printf("compressing archive of %s failed: %v\n", bookmarkID, err)
+			return
+		}
+
+		if err := s.store.SaveBookmarkArchive(context.Background(), &model.BookmarkArchive{
+			BookmarkID: bookmarkID,
+			UserID: userID,
+			Mode: snapshot.Mode,
+			ContentType: snapshot.ContentType,
+			Size: int64(len(snapshot.Content)),
+			StoredSize: int64(len(data)),
+			Data: data,
+		}, s.archiveQuota); err != nil {
+			log.Printf("saving archive of %s failed: %v\n", bookmarkID, err)
+		}
+	}()
+}
+
+func (s *server) postArchiveHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	bookmarkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	mode := c.DefaultQuery("mode", archive.ModeHTML)
+	if mode != archive.ModeHTML && mode != archive.ModeText {
+		c.JSON(400, gin.H{"error": "unsupported archive mode"})
+		return
+	}
+
+	bookmark, err := s.store.GetBookmarkByID(context.Background(), id, bookmarkID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": "bookmark not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	usage, err := s.store.GetArchiveUsage(context.Background(), id)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	if s.archiveQuota > 0 && usage.StoredSize >= s.archiveQuota {
+		c.JSON(507, gin.H{"error": model.ErrQuotaExceeded.Error()})
+		return
+	}
+
+	s.archiveBookmark(id, bookmark.ID, bookmark.URL, mode)
+
+	c.JSON(202, gin.H{"status": "archiving", "mode": mode})
+}
+
+func (s *server) getArchiveHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	bookmarkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	snapshot, err := s.store.GetBookmarkArchive(context.Background(), id, bookmarkID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": "archive not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.Header("Content-Security-Policy", archiveContentSecurityPolicy)
+	c.Header("Vary", "Accept-Encoding")
+
+	if strings.Contains(c.GetHeader("Accept-Encoding"), "gzip") {
+		c.Header("Content-Encoding", "gzip")
+		c.Data(200, snapshot.ContentType, snapshot.Data)
+		return
+	}
+
+	content, err := archive.Decompress(snapshot.Data)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.Data(200, snapshot.ContentType, content)
+}
+
+func (s *server) deleteArchiveHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	bookmarkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := s.store.DeleteBookmarkArchive(context.Background(), id, bookmarkID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": "archive not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(204, nil)
+}
+
+func (s *server) archiveUsageHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	usage, err := s.store.GetArchiveUsage(context.Background(), id)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+	usage.Quota = s.archiveQuota
+
+	c.JSON(200, usage)
+}
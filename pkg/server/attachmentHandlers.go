@@ -0,0 +1,272 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/box1bs/TelegraphicVault/pkg/blob"
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// room for the multipart envelope around the file itself
+	multipartOverhead = 1 << 20
+	sniffLength = 512
+	// downloads are served from our origin, so nothing in them may run
+	attachmentContentSecurityPolicy = "sandbox; default-src 'none'; img-src data:; style-src 'unsafe-inline'"
+)
+
+// types shown by browsers without running anything; everything else is downloaded
+var inlineTypes = []string{"application/pdf", "image/png", "image/jpeg", "image/gif", "image/webp", "image/bmp", "text/plain"}
+
+// runBlobSweeper removes stored files that no attachment refers to anymore.
+func (s *server) runBlobSweeper() {
+	if s.attachments.SweepInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.attachments.SweepInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := s.store.PurgeOrphanBlobs(context.Background(), func(key string) error {
+			return s.blobs.Delete(context.Background(), key)
+		}); err != nil {
+			log.Printf("sweeping blobs failed: %v\n", err)
+		}
+	}
+}
+
+func (s *server) postAttachmentHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	noteID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if s.attachments.MaxFileSize > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, s.attachments.MaxFileSize + multipartOverhead)
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(413, gin.H{"error": "file too large"})
+			return
+		}
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+	defer file.Close()
+
+	if s.attachments.MaxFileSize > 0 && header.Size > s.attachments.MaxFileSize {
+		c.JSON(413, gin.H{"error": "file too large"})
+		return
+	}
+
+	// the type is taken from the content, never from the client
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+	contentType := http.DetectContentType(head[:n])
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if len(s.attachments.AllowedTypes) > 0 && !slices.Contains(s.attachments.AllowedTypes, mediaType) {
+		c.JSON(415, gin.H{"error": "unsupported file type", "content_type": mediaType})
+		return
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+	digest := hex.EncodeToString(hash.Sum(nil))
+
+	attachment := &model.Attachment{
+		NoteID: noteID,
+		UserID: id,
+		Filename: attachmentFilename(header.Filename),
+		ContentType: contentType,
+		Size: header.Size,
+		SHA256: digest,
+	}
+
+	if err := s.store.CreateAttachment(context.Background(), attachment, s.attachments.UserQuota, func() error {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		return s.blobs.Put(context.Background(), digest, file, header.Size, contentType)
+	}, func() error {
+		return s.blobs.Delete(context.Background(), digest)
+	}); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(404, gin.H{"error": "note not found"})
+		case errors.Is(err, model.ErrQuotaExceeded):
+			c.JSON(507, gin.H{"error": err.Error()})
+		default:
+			log.Printf("saving attachment of %s failed: %v\n", noteID, err)
+			c.JSON(500, gin.H{"error": "internal error"})
+		}
+		return
+	}
+
+	c.JSON(201, attachment)
+}
+
+// attachmentFilename keeps the base name of an uploaded file without control characters.
+func attachmentFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	return name
+}
+
+func (s *server) getAllAttachmentHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	noteID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	attachments, err := s.store.ListAttachments(context.Background(), id, noteID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": "note not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(200, attachments)
+}
+
+func (s *server) getAttachmentHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	attachmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	attachment, err := s.store.GetAttachment(context.Background(), id, attachmentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": "attachment not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	content, err := s.blobs.Get(c.Request.Context(), attachment.SHA256)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			log.Printf("content of attachment %s is missing\n", attachment.ID)
+		}
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+	defer content.Close()
+
+	mediaType, _, _ := mime.ParseMediaType(attachment.ContentType)
+	disposition := "attachment"
+	if slices.Contains(inlineTypes, mediaType) {
+		disposition = "inline"
+	}
+
+	c.DataFromReader(200, attachment.Size, attachment.ContentType, content, map[string]string{
+		"Content-Disposition": mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}),
+		"Content-Security-Policy": attachmentContentSecurityPolicy,
+	})
+}
+
+func (s *server) deleteAttachmentHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	attachmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := s.store.DeleteAttachment(context.Background(), id, attachmentID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": "attachment not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(204, nil)
+}
+
+func (s *server) attachmentUsageHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	usage, err := s.store.GetAttachmentUsage(context.Background(), id)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+	usage.Quota = s.attachments.UserQuota
+
+	c.JSON(200, usage)
+}
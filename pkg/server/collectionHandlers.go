@@ -0,0 +1,204 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/box1bs/TelegraphicVault/pkg/database"
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func writeCollectionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, model.ErrInvalidCollection):
+		c.JSON(400, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(404, gin.H{"error": "collection not found"})
+	default:
+		c.JSON(500, gin.H{"error": "internal error"})
+	}
+}
+
+// parseCollectionFilter reads the "collection" and "recursive" list parameters.
+func parseCollectionFilter(c *gin.Context) (uuid.UUID, bool, error) {
+	raw := c.Query("collection")
+	if raw == "" {
+		return uuid.Nil, false, nil
+	}
+
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+
+	return id, c.Query("recursive") == "true", nil
+}
+
+func (s *server) getAllCollectionHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	collections, err := s.store.ListCollections(context.Background(), id)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(200, collections)
+}
+
+func (s *server) postCollectionHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	var payload struct {
+		Name     string     `json:"name"`
+		Icon     string     `json:"icon"`
+		ParentID *uuid.UUID `json:"parent_id"`
+	}
+
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	collection := &model.Collection{
+		ID: uuid.New(),
+		Name: payload.Name,
+		Icon: payload.Icon,
+		ParentID: payload.ParentID,
+		UserID: id,
+	}
+	if err := s.store.CreateCollection(context.Background(), collection); err != nil {
+		writeCollectionError(c, err)
+		return
+	}
+
+	c.JSON(201, collection)
+}
+
+func (s *server) putCollectionHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	collectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	var payload struct {
+		Name string `json:"name"`
+		Icon string `json:"icon"`
+	}
+
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	collection, err := s.store.UpdateCollection(context.Background(), id, collectionID, payload.Name, payload.Icon)
+	if err != nil {
+		writeCollectionError(c, err)
+		return
+	}
+
+	c.JSON(200, collection)
+}
+
+func (s *server) moveCollectionHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	collectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	var payload struct {
+		ParentID *uuid.UUID `json:"parent_id"`
+		Position int        `json:"position"`
+	}
+
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	collection, err := s.store.MoveCollection(context.Background(), id, collectionID, payload.ParentID, payload.Position)
+	if err != nil {
+		writeCollectionError(c, err)
+		return
+	}
+
+	c.JSON(200, collection)
+}
+
+func (s *server) deleteCollectionHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	collectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	policy := c.DefaultQuery("policy", storage.CollectionMoveToParent)
+	if err := s.store.DeleteCollection(context.Background(), id, collectionID, policy); err != nil {
+		writeCollectionError(c, err)
+		return
+	}
+
+	c.JSON(204, nil)
+}
+
+func (s *server) moveToCollectionHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	var payload struct {
+		CollectionID *uuid.UUID  `json:"collection_id"`
+		Notes        []uuid.UUID `json:"notes"`
+		Bookmarks    []uuid.UUID `json:"bookmarks"`
+	}
+
+	if err := c.ShouldBindJSON(&payload); err != nil || len(payload.Notes)+len(payload.Bookmarks) == 0 {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := s.store.MoveToCollection(context.Background(), id, payload.CollectionID, payload.Notes, payload.Bookmarks); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": "collection or item not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(204, nil)
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (s *server) duplicateBookmarkHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	groups, err := s.store.FindDuplicateBookmarks(context.Background(), id)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(200, groups)
+}
+
+func (s *server) mergeBookmarkHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	var payload struct {
+		Target  uuid.UUID   `json:"target"`
+		Sources []uuid.UUID `json:"sources"`
+	}
+
+	if err := c.ShouldBindJSON(&payload); err != nil || payload.Target == uuid.Nil || len(payload.Sources) == 0 {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	bookmark, err := s.store.MergeBookmarks(context.Background(), id, payload.Target, payload.Sources)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": "bookmark not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(200, bookmark)
+}
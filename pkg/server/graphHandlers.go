@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/box1bs/TelegraphicVault/pkg/database"
+	"github.com/box1bs/TelegraphicVault/pkg/graph"
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const defaultGraphDepth = 2
+
+// writeGraph responds with g in the format requested by the "format" query parameter, JSON by default.
+func writeGraph(c *gin.Context, g *graph.Graph, name string) {
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(200, g)
+	case "graphml":
+		c.Header("Content-Type", "application/graphml+xml")
+		c.Header("Content-Disposition", `attachment; filename="` + name + `.graphml"`)
+		c.Status(200)
+		if err := graph.WriteGraphML(c.Writer, g); err != nil {
+			log.Printf("graph export failed: %v\n", err)
+		}
+	case "dot":
+		c.Header("Content-Type", "text/vnd.graphviz")
+		c.Header("Content-Disposition", `attachment; filename="` + name + `.dot"`)
+		c.Status(200)
+		if err := graph.WriteDOT(c.Writer, g, name); err != nil {
+			log.Printf("graph export failed: %v\n", err)
+		}
+	default:
+		c.JSON(400, gin.H{"error": "unsupported format"})
+	}
+}
+
+// knowledgeGraphHandler exports notes, bookmarks and tags with note links, tag assignments and
+// note-to-bookmark references, optionally limited to the neighbourhood of a focus node.
+func (s *server) knowledgeGraphHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	collection, _, err := parseCollectionFilter(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid collection filter"})
+		return
+	}
+
+	depth, err := strconv.Atoi(c.DefaultQuery("depth", strconv.Itoa(defaultGraphDepth)))
+	if err != nil || depth < 0 {
+		c.JSON(400, gin.H{"error": "invalid depth"})
+		return
+	}
+
+	kg, err := s.store.GetKnowledgeGraph(context.Background(), storage.GraphFilter{
+		UserID: id,
+		Tag: c.Query("tag"),
+		Collection: collection,
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": "tag not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	g := buildKnowledgeGraph(kg)
+	if focus := c.Query("focus"); focus != "" {
+		if g = g.Neighbourhood(focus, depth); g == nil {
+			c.JSON(404, gin.H{"error": "focus node not found"})
+			return
+		}
+	}
+
+	writeGraph(c, g, "vault")
+}
+
+// node ids are prefixed with their kind, e.g. "note:<id>", so a focus can name any of them
+func buildKnowledgeGraph(kg *storage.KnowledgeGraph) *graph.Graph {
+	g := &graph.Graph{Directed: true, Nodes: []graph.Node{}, Edges: []graph.Edge{}}
+	tags := make(map[uuid.UUID]bool)
+
+	addTags := func(source string, itemTags []model.Tag) {
+		for _, tag := range itemTags {
+			if !tags[tag.ID] {
+				tags[tag.ID] = true
+				g.Nodes = append(g.Nodes, graph.Node{ID: "tag:" + tag.ID.String(), Label: tag.Name, Kind: "tag", Weight: tag.Count})
+			}
+			g.Edges = append(g.Edges, graph.Edge{Source: source, Target: "tag:" + tag.ID.String(), Kind: "tag"})
+		}
+	}
+
+	for _, note := range kg.Notes {
+		g.Nodes = append(g.Nodes, graph.Node{ID: "note:" + note.ID.String(), Label: note.Title, Kind: "note"})
+		addTags("note:" + note.ID.String(), note.Tags)
+	}
+
+	for _, bookmark := range kg.Bookmarks {
+		label := bookmark.Title
+		if label == "" {
+			label = bookmark.URL
+		}
+		g.Nodes = append(g.Nodes, graph.Node{ID: "bookmark:" + bookmark.ID.String(), Label: label, Kind: "bookmark"})
+		addTags("bookmark:" + bookmark.ID.String(), bookmark.Tags)
+	}
+
+	for _, link := range kg.Links {
+		g.Edges = append(g.Edges, graph.Edge{Source: "note:" + link.SourceID.String(), Target: "note:" + link.TargetID.String(), Kind: "link"})
+	}
+
+	for _, ref := range kg.References {
+		g.Edges = append(g.Edges, graph.Edge{Source: "note:" + ref.NoteID.String(), Target: "bookmark:" + ref.BookmarkID.String(), Kind: "reference"})
+	}
+
+	return g
+}
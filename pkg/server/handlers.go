@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"github.com/box1bs/TelegraphicVault/pkg/archive"
 	"github.com/box1bs/TelegraphicVault/pkg/auth"
 	"github.com/box1bs/TelegraphicVault/pkg/database"
 	"github.com/box1bs/TelegraphicVault/pkg/model"
+	"slices"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -22,7 +24,47 @@ func (s *server) getAllBookmarkHandler(c *gin.Context) {
 		return
 	}
 
-	bookmarks, err := s.store.ListBookmarks(context.Background(), storage.BookmarkFilter{UserID: id})
+	health := c.Query("health")
+	if health != "" && health != model.LinkHealthOK && health != model.LinkHealthRedirected && health != model.LinkHealthBroken {
+		c.JSON(400, gin.H{"error": "invalid health filter"})
+		return
+	}
+
+	status := c.Query("status")
+	if status != "" && !slices.Contains(model.ReadStatuses, status) {
+		c.JSON(400, gin.H{"error": "invalid status filter"})
+		return
+	}
+
+	collection, recursive, err := parseCollectionFilter(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid collection filter"})
+		return
+	}
+
+	pinned, err := parseFlagFilter(c, "pinned")
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid pinned filter"})
+		return
+	}
+
+	favorite, err := parseFlagFilter(c, "favorite")
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid favorite filter"})
+		return
+	}
+
+	bookmarks, err := s.store.ListBookmarks(context.Background(), storage.BookmarkFilter{
+		UserID: id,
+		Tag: c.Query("tag"),
+		Tags: parseTagQuery(c),
+		Health: health,
+		Status: status,
+		Collection: collection,
+		Recursive: recursive,
+		Pinned: pinned,
+		Favorite: favorite,
+	})
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(404, gin.H{"error": "bookmark not found"})
@@ -47,6 +89,7 @@ func (s *server) postBookmarkHandler(c *gin.Context) {
 		Title 		string 			`json:"title"`
 		Description string 			`json:"description"`
 		Tags 		[]string 		`json:"tags"`
+		Archive 	string 			`json:"archive"`
 	}
 
 	if err := json.NewDecoder(c.Request.Body).Decode(&payload); err != nil {
@@ -54,6 +97,11 @@ func (s *server) postBookmarkHandler(c *gin.Context) {
 		return
 	}
 
+	if payload.Url == "" || (payload.Archive != "" && payload.Archive != archive.ModeHTML && payload.Archive != archive.ModeText) {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
 	bookmark := model.Bookmark{
 		ID: uuid.New(),
 		URL: payload.Url,
@@ -61,9 +109,22 @@ func (s *server) postBookmarkHandler(c *gin.Context) {
 		Description: payload.Description,
 		UserID: id,
 	}
-	s.store.CreateBookmark(context.Background(), bookmark)
+	if err := s.store.CreateBookmark(context.Background(), bookmark); err != nil {
+		if errors.Is(err, model.ErrAlreadyExists) {
+			c.JSON(409, gin.H{"error": "bookmark already exists"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
 	s.store.AddTagToBookmark(context.Background(), &bookmark, payload.Tags)
 
+	s.processBookmarkPage(bookmark.ID, bookmark.URL)
+
+	if payload.Archive != "" {
+		s.archiveBookmark(id, bookmark.ID, bookmark.URL, payload.Archive)
+	}
+
 	c.JSON(201, bookmark)
 }
 
@@ -137,7 +198,7 @@ func (s *server) searchBookmarkHandler(c *gin.Context) {
 		return
 	}
 
-	bookmarks, err := s.store.SearchBookmark(context.Background(), id, query)
+	bookmarks, err := s.store.SearchBookmark(context.Background(), storage.BookmarkFilter{UserID: id, Tags: parseTagQuery(c)}, query)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(404, gin.H{"error": "note not found"})
@@ -150,6 +211,43 @@ func (s *server) searchBookmarkHandler(c *gin.Context) {
 	c.JSON(200, bookmarks)
 }
 
+func (s *server) readerBookmarkHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	bookmarkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	bookmark, err := s.store.GetBookmarkByID(context.Background(), id, bookmarkID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": "bookmark not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	if bookmark.ExtractedAt == nil {
+		c.JSON(404, gin.H{"error": "article not extracted yet"})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"id": bookmark.ID,
+		"url": bookmark.URL,
+		"title": bookmark.Title,
+		"text": bookmark.ArticleText,
+		"extracted_at": bookmark.ExtractedAt,
+	})
+}
+
 func (s *server) getAllNoteHandler(c *gin.Context) {
 	id, err := extractUserId(c)
 	if err != nil {
@@ -157,7 +255,40 @@ func (s *server) getAllNoteHandler(c *gin.Context) {
 		return
 	}
 
-	notes, err := s.store.ListNotes(context.Background(), storage.NoteFilter{UserID: id})
+	collection, recursive, err := parseCollectionFilter(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid collection filter"})
+		return
+	}
+
+	pinned, err := parseFlagFilter(c, "pinned")
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid pinned filter"})
+		return
+	}
+
+	favorite, err := parseFlagFilter(c, "favorite")
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid favorite filter"})
+		return
+	}
+
+	noteType := c.Query("type")
+	if noteType != "" && noteType != model.NoteTypeNote && noteType != model.NoteTypeJournal {
+		c.JSON(400, gin.H{"error": "invalid type filter"})
+		return
+	}
+
+	notes, err := s.store.ListNotes(context.Background(), storage.NoteFilter{
+		UserID: id,
+		Tag: c.Query("tag"),
+		Tags: parseTagQuery(c),
+		Collection: collection,
+		Recursive: recursive,
+		Pinned: pinned,
+		Favorite: favorite,
+		Type: noteType,
+	})
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(404, gin.H{"error": "note not found"})
@@ -194,10 +325,25 @@ func (s *server) postNoteHandler(c *gin.Context) {
 		Content: payload.Content,
 		UserID: id,
 	}
-	s.store.CreateNote(context.Background(), note)
-	s.store.AddTagToNote(context.Background(), &note, payload.Tags)
+	if err := s.store.CreateNote(context.Background(), note, payload.Tags); err != nil {
+		switch {
+		case errors.Is(err, model.ErrAlreadyExists):
+			c.JSON(409, gin.H{"error": err.Error()})
+		case errors.Is(err, model.ErrInvalidTag):
+			c.JSON(400, gin.H{"error": "invalid tag"})
+		default:
+			c.JSON(500, gin.H{"error": "internal error"})
+		}
+		return
+	}
 
-	c.JSON(201, note)
+	created, err := s.store.GetNoteByID(context.Background(), id, note.ID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(201, created)
 }
 
 func (s *server) putNoteHandler(c *gin.Context) {
@@ -212,6 +358,8 @@ func (s *server) putNoteHandler(c *gin.Context) {
 		NewTitle 		string `json:"new_title"`
 		Content 		string `json:"content"`
 		Tags 			[]string `json:"tags"`
+		// RewriteLinks changes [[links]] to the old title in other notes on a rename
+		RewriteLinks 	bool `json:"rewrite_links"`
 	}
 
 	if err := c.ShouldBindJSON(&payload); err != nil {
@@ -219,12 +367,20 @@ func (s *server) putNoteHandler(c *gin.Context) {
 		return
 	}
 
-	note, err := s.store.UpdateNote(context.Background(), id, payload.CurrentTitle, payload.NewTitle, payload.Content, payload.Tags)
+	note, err := s.store.UpdateNote(context.Background(), id, payload.CurrentTitle, payload.NewTitle, payload.Content, payload.Tags, payload.RewriteLinks)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(404, gin.H{"error": "note not found"})
 			return
 		}
+		if errors.Is(err, model.ErrInvalidTitle) {
+			c.JSON(400, gin.H{"error": "title cannot be linked"})
+			return
+		}
+		if errors.Is(err, model.ErrAlreadyExists) {
+			c.JSON(409, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(500, gin.H{"error": "internal error"})
 		return
 	}
@@ -270,7 +426,13 @@ func (s *server) searchNoteHandler(c *gin.Context) {
 		return
 	}
 
-	note, err := s.store.GetNote(context.Background(), id, query)
+	noteType := c.Query("type")
+	if noteType != "" && noteType != model.NoteTypeNote && noteType != model.NoteTypeJournal {
+		c.JSON(400, gin.H{"error": "invalid type filter"})
+		return
+	}
+
+	note, err := s.store.SearchNote(context.Background(), storage.NoteFilter{UserID: id, Tags: parseTagQuery(c), Type: noteType}, query)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(404, gin.H{"error": "note not found"})
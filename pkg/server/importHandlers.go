@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/box1bs/TelegraphicVault/pkg/database"
+	"github.com/box1bs/TelegraphicVault/pkg/importer"
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const maxImportSize = 32 << 20
+
+// importFile returns the uploaded export, sent either as the "file" field of a multipart form or as the raw body.
+func importFile(c *gin.Context) (io.ReadCloser, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+	if file, err := c.FormFile("file"); err == nil {
+		return file.Open()
+	} else if !errors.Is(err, http.ErrNotMultipart) {
+		return nil, err
+	}
+
+	return c.Request.Body, nil
+}
+
+// entryBookmarks converts parsed entries into bookmarks owned by userID and reports those without a usable URL.
+func entryBookmarks(userID uuid.UUID, entries []importer.Entry) ([]*model.Bookmark, []storage.ImportFailure) {
+	var bookmarks []*model.Bookmark
+	failed := []storage.ImportFailure{}
+
+	for _, entry := range entries {
+		u, err := url.Parse(entry.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			failed = append(failed, storage.ImportFailure{URL: entry.URL, Error: "unsupported url"})
+			continue
+		}
+
+		bookmark := &model.Bookmark{
+			URL: entry.URL,
+			Title: entry.Title,
+			Description: entry.Description,
+			UserID: userID,
+			CreatedAt: entry.AddedAt,
+		}
+		// the formats record whether an item was read but not when, so read_at stays empty
+		if entry.Read != nil && *entry.Read {
+			bookmark.ReadStatus = model.ReadStatusRead
+		}
+		for _, name := range entry.Tags {
+			bookmark.Tags = append(bookmark.Tags, model.Tag{Name: name})
+		}
+		bookmarks = append(bookmarks, bookmark)
+	}
+
+	return bookmarks, failed
+}
+
+// importHandler imports the export file of the format named in the path. With dry_run=true
+// nothing is saved and the report contains a preview of every row.
+func (s *server) importHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	parse, ok := importer.Parsers[c.Param("format")]
+	if !ok {
+		c.JSON(404, gin.H{"error": "unsupported import format"})
+		return
+	}
+
+	policy := c.DefaultQuery("policy", storage.ImportSkip)
+	if policy != storage.ImportSkip && policy != storage.ImportOverwrite && policy != storage.ImportMerge {
+		c.JSON(400, gin.H{"error": "unsupported duplicate policy"})
+		return
+	}
+
+	file, err := importFile(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+	defer file.Close()
+
+	entries, err := parse(file)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	bookmarks, failed := entryBookmarks(id, entries)
+	report, err := s.store.ImportBookmarks(context.Background(), id, bookmarks, storage.ImportOptions{
+		Policy: policy,
+		DryRun: c.Query("dry_run") == "true",
+	})
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+	report.Failed = append(failed, report.Failed...)
+
+	c.JSON(200, report)
+}
+
+func (s *server) exportNetscapeHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	bookmarks, err := s.store.ListBookmarks(context.Background(), storage.BookmarkFilter{UserID: id, Tag: c.Query("tag"), Tags: parseTagQuery(c)})
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="bookmarks.html"`)
+	c.Status(200)
+	if err := importer.WriteNetscape(c.Writer, bookmarks); err != nil {
+		log.Printf("bookmark export failed: %v\n", err)
+	}
+}
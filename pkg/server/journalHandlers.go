@@ -0,0 +1,205 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const journalMonthFormat = "2006-01"
+
+// journalTitleAttempts bounds the numbered titles tried for a journal note whose date is already a note title.
+const journalTitleAttempts = 10
+
+// defaultJournalTemplate is used until the user picks a journal template in the settings.
+var defaultJournalTemplate = model.NoteTemplate{
+	Name: "journal",
+	Content: "# {{date}}, {{weekday}}\n\n",
+}
+
+type journalStreak struct {
+	Current 	int 	`json:"current"`
+	Longest 	int 	`json:"longest"`
+	Total 		int 	`json:"total"`
+	LastEntry 	string 	`json:"last_entry,omitempty"`
+}
+
+// userLocation is the timezone of the user, UTC when it is not set or unknown.
+func userLocation(user *model.User) *time.Location {
+	location, err := time.LoadLocation(user.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// journalHandler returns the journal note of a day, creating it from the journal template on first access.
+func (s *server) journalHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	user, err := s.store.FindByID(id)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	now := time.Now().In(userLocation(user))
+	at := now
+	if param := c.Param("date"); param != "today" {
+		date, err := time.ParseInLocation(time.DateOnly, param, now.Location())
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid date"})
+			return
+		}
+		if date.Format(time.DateOnly) != now.Format(time.DateOnly) {
+			at = date
+		}
+	}
+	day := at.Format(time.DateOnly)
+
+	note, err := s.store.GetJournalNote(context.Background(), id, day)
+	if err == nil {
+		c.JSON(200, note)
+		return
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	template := &defaultJournalTemplate
+	if user.JournalTemplateID != nil {
+		custom, err := s.store.GetTemplate(context.Background(), id, *user.JournalTemplateID)
+		switch {
+		case err == nil:
+			template = custom
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(500, gin.H{"error": "internal error"})
+			return
+		}
+	}
+
+	note, err = s.createNoteFromTemplate(model.Note{
+		UserID: id,
+		Title: day,
+		Type: model.NoteTypeJournal,
+		JournalDate: &day,
+	}, template, at, nil, nil)
+	if err != nil {
+		// a concurrent request created the same day first and the unique index rejected this one
+		if existing, err := s.store.GetJournalNote(context.Background(), id, day); err == nil {
+			c.JSON(200, existing)
+			return
+		}
+		writeTemplateError(c, err)
+		return
+	}
+
+	c.JSON(201, note)
+}
+
+func (s *server) journalCalendarHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	user, err := s.store.FindByID(id)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	month := time.Now().In(userLocation(user))
+	if param := c.Query("month"); param != "" {
+		month, err = time.Parse(journalMonthFormat, param)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid month"})
+			return
+		}
+	}
+	first := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1)
+
+	days, err := s.store.ListJournalDays(context.Background(), id, first.Format(time.DateOnly), last.Format(time.DateOnly))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"month": first.Format(journalMonthFormat),
+		"days": days,
+	})
+}
+
+func (s *server) journalStreakHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	user, err := s.store.FindByID(id)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	today := time.Now().In(userLocation(user)).Format(time.DateOnly)
+	days, err := s.store.ListJournalDays(context.Background(), id, "", today)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	dates := make([]string, 0, len(days))
+	for _, day := range days {
+		dates = append(dates, day.Date)
+	}
+
+	c.JSON(200, streakOf(dates, today))
+}
+
+// streakOf counts runs of consecutive days in dates, sorted YYYY-MM-DD strings up to today.
+// The current streak still counts when the last entry was made yesterday.
+func streakOf(dates []string, today string) journalStreak {
+	streak := journalStreak{Total: len(dates)}
+	if len(dates) == 0 {
+		return streak
+	}
+
+	run := 0
+	var previous time.Time
+	for _, date := range dates {
+		day, err := time.Parse(time.DateOnly, date)
+		if err != nil {
+			continue
+		}
+		if run > 0 && previous.AddDate(0, 0, 1).Equal(day) {
+			run++
+		} else {
+			run = 1
+		}
+		previous = day
+		streak.Longest = max(streak.Longest, run)
+	}
+
+	streak.LastEntry = dates[len(dates) - 1]
+	end, err := time.Parse(time.DateOnly, today)
+	if err == nil && (previous.Equal(end) || previous.AddDate(0, 0, 1).Equal(end)) {
+		streak.Current = run
+	}
+
+	return streak
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/box1bs/TelegraphicVault/pkg/database"
+	"github.com/box1bs/TelegraphicVault/pkg/fetch"
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// runLinkChecker re-checks the least recently checked bookmarks on start and then every Interval,
+// sharing fetch slots with page processing and archiving.
+func (s *server) runLinkChecker() {
+	if s.linkCheck.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.linkCheck.Interval)
+	defer ticker.Stop()
+
+	s.checkLinks()
+	for range ticker.C {
+		s.checkLinks()
+	}
+}
+
+func (s *server) checkLinks() {
+	bookmarks, err := s.store.ListBookmarksToCheck(context.Background(), time.Now().Add(-s.linkCheck.RecheckAfter), s.linkCheck.BatchSize)
+	if err != nil {
+		log.Printf("listing bookmarks to check failed: %v\n", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, bookmark := range bookmarks {
+		wg.Add(1)
+		s.fetchSlots <- struct{}{}
+		go func(bookmark *model.Bookmark) {
+			defer wg.Done()
+			defer func() { <-s.fetchSlots }()
+
+			check := checkLink(s.fetcher, bookmark.URL)
+			if err := s.store.SaveLinkCheck(context.Background(), bookmark.ID, check, s.linkCheck.FailureThreshold); err != nil {
+				log.Printf("saving link check for %s failed: %v\n", bookmark.ID, err)
+			}
+		}(bookmark)
+	}
+	wg.Wait()
+}
+
+// checkLink requests uri with HEAD and falls back to GET for servers that reject HEAD.
+func checkLink(fetcher *fetch.Fetcher, uri string) storage.LinkCheck {
+	resp, err := fetcher.Head(context.Background(), uri)
+	if err != nil || resp.StatusCode == 403 || resp.StatusCode == 405 || resp.StatusCode == 501 {
+		resp, err = fetcher.Get(context.Background(), uri)
+	}
+	if err != nil {
+		return storage.LinkCheck{Failed: true}
+	}
+
+	check := storage.LinkCheck{StatusCode: resp.StatusCode, Failed: resp.StatusCode >= 400}
+	if requested, err := url.Parse(uri); err == nil && resp.URL != requested.String() {
+		check.RedirectURL = resp.URL
+	}
+	return check
+}
+
+func (s *server) linkHealthHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	broken, err := s.store.ListBookmarks(context.Background(), storage.BookmarkFilter{UserID: id, Health: model.LinkHealthBroken})
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	redirected, err := s.store.ListBookmarks(context.Background(), storage.BookmarkFilter{UserID: id, Health: model.LinkHealthRedirected})
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(200, gin.H{"broken": broken, "redirected": redirected})
+}
+
+func (s *server) applyRedirectHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	bookmarkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	bookmark, err := s.store.ApplyBookmarkRedirect(context.Background(), id, bookmarkID)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(404, gin.H{"error": "bookmark not found"})
+		case errors.Is(err, model.ErrNoRedirect):
+			c.JSON(409, gin.H{"error": err.Error()})
+		case errors.Is(err, model.ErrAlreadyExists):
+			c.JSON(409, gin.H{"error": "bookmark with the redirect url already exists"})
+		default:
+			c.JSON(500, gin.H{"error": "internal error"})
+		}
+		return
+	}
+
+	c.JSON(200, bookmark)
+}
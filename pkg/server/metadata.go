@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"log"
+
+	"github.com/box1bs/TelegraphicVault/pkg/archive"
+	"github.com/box1bs/TelegraphicVault/pkg/fetch"
+
+	"github.com/google/uuid"
+)
+
+// processBookmarkPage downloads the page of a freshly saved bookmark in the background, fills in
+// its metadata and stores the extracted article text for search.
+// At most cap(s.fetchSlots) pages are fetched at the same time.
+func (s *server) processBookmarkPage(bookmarkID uuid.UUID, uri string) {
+	go func() {
+		s.fetchSlots <- struct{}{}
+		defer func() { <-s.fetchSlots }()
+
+		resp, err := s.fetcher.Get(context.Background(), uri)
+		if err != nil {
+			log.Printf("page fetch for %s failed: %v\n", bookmarkID, err)
+			return
+		}
+
+		if !fetch.IsHTML(resp) {
+			meta := fetch.ExtractMetadata(resp, nil)
+			if err := s.store.SaveBookmarkMetadata(context.Background(), bookmarkID, "", "", meta.CanonicalURL, "", meta.ContentType); err != nil {
+				log.Printf("saving metadata for %s failed: %v\n", bookmarkID, err)
+			}
+			return
+		}
+
+		doc, err := fetch.ParseHTML(resp)
+		if err != nil {
+			log.Printf("parsing page of %s failed: %v\n", bookmarkID, err)
+			return
+		}
+
+		meta := fetch.ExtractMetadata(resp, doc)
+		if err := s.store.SaveBookmarkMetadata(context.Background(), bookmarkID, meta.Title, meta.Description, meta.CanonicalURL, meta.FaviconURL, meta.ContentType); err != nil {
+			log.Printf("saving metadata for %s failed: %v\n", bookmarkID, err)
+		}
+
+		if err := s.store.SaveBookmarkArticle(context.Background(), bookmarkID, archive.Article(doc)); err != nil {
+			log.Printf("saving article for %s failed: %v\n", bookmarkID, err)
+		}
+	}()
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type flagsPayload struct {
+	Pinned   *bool `json:"pinned"`
+	Favorite *bool `json:"favorite"`
+}
+
+// positionPayload names the pinned neighbours an item was dropped between.
+type positionPayload struct {
+	After  *uuid.UUID `json:"after"`
+	Before *uuid.UUID `json:"before"`
+}
+
+// parseFlagFilter reads an optional boolean list parameter such as "pinned" or "favorite".
+func parseFlagFilter(c *gin.Context, name string) (*bool, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return &value, nil
+}
+
+func writePinError(c *gin.Context, err error, notFound string) {
+	switch {
+	case errors.Is(err, model.ErrInvalidPosition):
+		c.JSON(400, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(404, gin.H{"error": notFound})
+	default:
+		c.JSON(500, gin.H{"error": "internal error"})
+	}
+}
+
+func (s *server) putBookmarkFlagsHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	bookmarkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	var payload flagsPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	bookmark, err := s.store.SetBookmarkFlags(context.Background(), id, bookmarkID, payload.Pinned, payload.Favorite)
+	if err != nil {
+		writePinError(c, err, "bookmark not found")
+		return
+	}
+
+	c.JSON(200, bookmark)
+}
+
+func (s *server) putBookmarkPositionHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	bookmarkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	var payload positionPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	bookmark, err := s.store.ReorderBookmark(context.Background(), id, bookmarkID, payload.After, payload.Before)
+	if err != nil {
+		writePinError(c, err, "bookmark not found")
+		return
+	}
+
+	c.JSON(200, bookmark)
+}
+
+func (s *server) putNoteFlagsHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	noteID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	var payload flagsPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	note, err := s.store.SetNoteFlags(context.Background(), id, noteID, payload.Pinned, payload.Favorite)
+	if err != nil {
+		writePinError(c, err, "note not found")
+		return
+	}
+
+	c.JSON(200, note)
+}
+
+func (s *server) putNotePositionHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	noteID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	var payload positionPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	note, err := s.store.ReorderNote(context.Background(), id, noteID, payload.After, payload.Before)
+	if err != nil {
+		writePinError(c, err, "note not found")
+		return
+	}
+
+	c.JSON(200, note)
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/box1bs/TelegraphicVault/pkg/database"
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (s *server) putBookmarkStatusHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	bookmarkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	var payload struct {
+		Status string `json:"status"`
+	}
+
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	bookmark, err := s.store.SetBookmarkStatus(context.Background(), id, bookmarkID, payload.Status)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrInvalidStatus):
+			c.JSON(400, gin.H{"error": err.Error()})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(404, gin.H{"error": "bookmark not found"})
+		default:
+			c.JSON(500, gin.H{"error": "internal error"})
+		}
+		return
+	}
+
+	c.JSON(200, bookmark)
+}
+
+func (s *server) nextBookmarkHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	order := c.DefaultQuery("order", storage.QueueOldest)
+	bookmark, err := s.store.NextBookmarkToRead(context.Background(), storage.BookmarkFilter{UserID: id, Tags: parseTagQuery(c)}, order)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": "nothing to read"})
+			return
+		}
+		if errors.Is(err, model.ErrInvalidOrder) {
+			c.JSON(400, gin.H{"error": "unsupported order"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(200, bookmark)
+}
+
+func (s *server) bookmarkStatusCountHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	counts, err := s.store.CountBookmarksByStatus(context.Background(), id)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(200, counts)
+}
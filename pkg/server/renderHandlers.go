@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/box1bs/TelegraphicVault/pkg/markdown"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// renderedCSP forbids scripts and plugins in case the fragment is opened directly.
+const renderedCSP = "default-src 'none'; img-src https: data:; style-src 'unsafe-inline'"
+
+func (s *server) renderNoteHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	noteID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "html" {
+		c.JSON(400, gin.H{"error": "unsupported format"})
+		return
+	}
+
+	note, err := s.store.GetNoteByID(context.Background(), id, noteID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": "note not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	doc, err := markdown.Render(note.Content)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	if format == "html" {
+		c.Header("Content-Security-Policy", renderedCSP)
+		c.Data(200, "text/html; charset=utf-8", []byte(doc.HTML))
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"id": note.ID,
+		"title": note.Title,
+		"html": doc.HTML,
+		"toc": doc.TOC,
+	})
+}
+
+// previewHandler renders unsaved content, e.g. for a live preview in an editor.
+func (s *server) previewHandler(c *gin.Context) {
+	var payload struct {
+		Content string `json:"content"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	doc, err := markdown.Render(payload.Content)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(200, doc)
+}
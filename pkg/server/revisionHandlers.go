@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/box1bs/TelegraphicVault/pkg/diff"
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const diffContextLines = 3
+
+func (s *server) getNoteRevisionsHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	noteID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	revisions, err := s.store.ListNoteRevisions(context.Background(), id, noteID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": "note not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(200, revisions)
+}
+
+func (s *server) getNoteRevisionHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	noteID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	revision, err := s.store.GetNoteRevision(context.Background(), id, noteID, number)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": "revision not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(200, revision)
+}
+
+// diffNoteRevisionsHandler writes a unified diff of the content of two revisions.
+func (s *server) diffNoteRevisionsHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	noteID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	to, err := strconv.Atoi(c.Query("to"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	var revisions [2]*model.NoteRevision
+	for i, number := range []int{from, to} {
+		if revisions[i], err = s.store.GetNoteRevision(context.Background(), id, noteID, number); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(404, gin.H{"error": "revision not found"})
+				return
+			}
+			c.JSON(500, gin.H{"error": "internal error"})
+			return
+		}
+	}
+
+	unified := diff.Unified(
+		fmt.Sprintf("revision %d\t%s", from, revisions[0].Title),
+		fmt.Sprintf("revision %d\t%s", to, revisions[1].Title),
+		revisions[0].Content,
+		revisions[1].Content,
+		diffContextLines,
+	)
+
+	c.Data(200, "text/x-diff; charset=utf-8", []byte(unified))
+}
+
+func (s *server) restoreNoteRevisionHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	noteID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	note, err := s.store.RestoreNoteRevision(context.Background(), id, noteID, number)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrAlreadyExists):
+			c.JSON(409, gin.H{"error": "note with this title already exists"})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(404, gin.H{"error": "revision not found"})
+		default:
+			c.JSON(500, gin.H{"error": "internal error"})
+		}
+		return
+	}
+
+	c.JSON(200, note)
+}
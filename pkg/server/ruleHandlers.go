@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	jobRunning 	= "running"
+	jobDone 	= "done"
+	jobFailed 	= "failed"
+)
+
+type ruleJob struct {
+	Status 		string 		`json:"status"`
+	StartedAt 	time.Time 	`json:"started_at"`
+	FinishedAt 	*time.Time 	`json:"finished_at,omitempty"`
+	Matched 	int64 		`json:"matched"`
+	Error 		string 		`json:"error,omitempty"`
+}
+
+type rulePayload struct {
+	Name 		string 	`json:"name"`
+	Target 		string 	`json:"target"`
+	Field 		string 	`json:"field"`
+	Condition 	string 	`json:"condition"`
+	Pattern 	string 	`json:"pattern"`
+	Tag 		string 	`json:"tag"`
+	Enabled 	*bool 	`json:"enabled"`
+}
+
+func (p rulePayload) toRule(userID uuid.UUID) *model.TagRule {
+	return &model.TagRule{
+		Name: p.Name,
+		Target: p.Target,
+		Field: p.Field,
+		Condition: p.Condition,
+		Pattern: p.Pattern,
+		Tag: p.Tag,
+		Enabled: p.Enabled == nil || *p.Enabled,
+		UserID: userID,
+	}
+}
+
+func (s *server) getAllRuleHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	tagRules, err := s.store.ListTagRules(context.Background(), id)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(200, tagRules)
+}
+
+func (s *server) postRuleHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	var payload rulePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	rule := payload.toRule(id)
+	rule.ID = uuid.New()
+	if err := s.store.CreateTagRule(context.Background(), rule); err != nil {
+		writeRuleError(c, err)
+		return
+	}
+
+	c.JSON(201, rule)
+}
+
+func (s *server) putRuleHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	var payload struct {
+		ID uuid.UUID `json:"id"`
+		rulePayload
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil || payload.ID == uuid.Nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	rule := payload.toRule(id)
+	rule.ID = payload.ID
+	updated, err := s.store.UpdateTagRule(context.Background(), rule)
+	if err != nil {
+		writeRuleError(c, err)
+		return
+	}
+
+	c.JSON(200, updated)
+}
+
+func (s *server) deleteRuleHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	ruleID, err := uuid.Parse(c.Query("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := s.store.DeleteTagRule(context.Background(), id, ruleID); err != nil {
+		writeRuleError(c, err)
+		return
+	}
+
+	c.JSON(204, nil)
+}
+
+// applyRulesHandler starts re-applying the user's rules to all existing items in the background.
+func (s *server) applyRulesHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	job := ruleJob{Status: jobRunning, StartedAt: time.Now()}
+	if prev, loaded := s.ruleJobs.LoadOrStore(id, job); loaded {
+		if prev.(ruleJob).Status == jobRunning || !s.ruleJobs.CompareAndSwap(id, prev, job) {
+			c.JSON(409, gin.H{"error": "rules are already being applied"})
+			return
+		}
+	}
+
+	go func(userID uuid.UUID, job ruleJob) {
+		matched, err := s.store.ApplyTagRules(context.Background(), userID)
+		finishedAt := time.Now()
+		job.FinishedAt = &finishedAt
+		job.Matched = matched
+		job.Status = jobDone
+		if err != nil {
+			log.Printf("applying rules for %s failed: %v\n", userID, err)
+			job.Status = jobFailed
+			job.Error = "internal error"
+		}
+		s.ruleJobs.Store(userID, job)
+	}(id, job)
+
+	c.JSON(202, job)
+}
+
+func (s *server) applyRulesStatusHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	job, ok := s.ruleJobs.Load(id)
+	if !ok {
+		c.JSON(404, gin.H{"error": "job not found"})
+		return
+	}
+
+	c.JSON(200, job)
+}
+
+func writeRuleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(404, gin.H{"error": "rule not found"})
+	case errors.Is(err, model.ErrInvalidRule):
+		c.JSON(400, gin.H{"error": "invalid rule"})
+	default:
+		c.JSON(500, gin.H{"error": "internal error"})
+	}
+}
@@ -5,9 +5,12 @@ import (
 	"sync"
 	"time"
 
+	"github.com/box1bs/TelegraphicVault/pkg/archive"
 	"github.com/box1bs/TelegraphicVault/pkg/auth"
+	"github.com/box1bs/TelegraphicVault/pkg/blob"
 	"github.com/box1bs/TelegraphicVault/pkg/config"
 	"github.com/box1bs/TelegraphicVault/pkg/database"
+	"github.com/box1bs/TelegraphicVault/pkg/fetch"
 	"github.com/gin-contrib/cors"
 
 	"github.com/gin-gonic/gin"
@@ -17,20 +20,40 @@ type server struct {
 	store 		storage.Storage
 	auth 		*auth.AuthService
 	keyStore 	*sync.Map
+	ruleJobs 	*sync.Map
+	fetcher 	*fetch.Fetcher
+	fetchSlots 	chan struct{}
+	archiver 	*archive.Archiver
+	archiveQuota int64
+	linkCheck 	config.LinkCheckConfig
+	trash 		config.TrashConfig
+	blobs 		blob.Store
+	attachments config.AttachmentConfig
 	mu 			*sync.Mutex
 }
 
-func NewServer(store storage.Storage, conf *config.AuthConfig) *server {
+func NewServer(store storage.Storage, conf *config.AuthConfig, fetchConf *config.FetchConfig, archiveConf *config.ArchiveConfig, linkCheckConf *config.LinkCheckConfig, trashConf *config.TrashConfig, blobs blob.Store, attachmentConf *config.AttachmentConfig) *server {
+	fetcher := fetch.New(*fetchConf)
 	return &server{
 		store: store,
 		auth: auth.NewAuthService(conf, store),
 		keyStore: &sync.Map{},
+		ruleJobs: &sync.Map{},
+		fetcher: fetcher,
+		fetchSlots: make(chan struct{}, max(fetchConf.MaxConcurrent, 1)),
+		archiver: archive.NewArchiver(fetcher, *archiveConf),
+		archiveQuota: archiveConf.UserQuota,
+		linkCheck: *linkCheckConf,
+		trash: *trashConf,
+		blobs: blobs,
+		attachments: *attachmentConf,
 		mu: new(sync.Mutex),
 	}
 }
 
 func (s *server) Run() error {
 	r := gin.Default()
+	r.UseRawPath = true // keeps "%2F" in hierarchical tag names inside a single path segment
 	r.Use(cors.New(cors.Config{
         AllowOrigins:     []string{"http://localhost:5173"},
         AllowMethods:     []string{
@@ -83,6 +106,9 @@ func (s *server) Run() error {
 	})
 
 	s.registerRoutes(r)
+	go s.runLinkChecker()
+	go s.runTrashPurger()
+	go s.runBlobSweeper()
 	return r.Run()
 }
 
@@ -100,6 +126,20 @@ func (s *server) registerRoutes(r *gin.Engine) {
 			bookmarks.PUT("", s.putBookmarkHandler)
 			bookmarks.DELETE("", s.deleteBookmarkHandler)
 			bookmarks.GET("/search", s.searchBookmarkHandler)
+			bookmarks.GET("/archives", s.archiveUsageHandler)
+			bookmarks.GET("/health", s.linkHealthHandler)
+			bookmarks.GET("/next", s.nextBookmarkHandler)
+			bookmarks.GET("/status", s.bookmarkStatusCountHandler)
+			bookmarks.PUT("/:id/status", s.putBookmarkStatusHandler)
+			bookmarks.PUT("/:id/flags", s.putBookmarkFlagsHandler)
+			bookmarks.PUT("/:id/position", s.putBookmarkPositionHandler)
+			bookmarks.GET("/duplicates", s.duplicateBookmarkHandler)
+			bookmarks.POST("/merge", s.mergeBookmarkHandler)
+			bookmarks.POST("/:id/redirect", s.applyRedirectHandler)
+			bookmarks.GET("/:id/reader", s.readerBookmarkHandler)
+			bookmarks.GET("/:id/archive", s.getArchiveHandler)
+			bookmarks.POST("/:id/archive", s.postArchiveHandler)
+			bookmarks.DELETE("/:id/archive", s.deleteArchiveHandler)
 		}
 		
 		notes := app.Group("/notes")
@@ -109,6 +149,106 @@ func (s *server) registerRoutes(r *gin.Engine) {
 			notes.PUT("", s.putNoteHandler)
 			notes.DELETE("", s.deleteNoteHandler)
 			notes.GET("/search", s.searchNoteHandler)
+			notes.POST("/from-template/:id", s.noteFromTemplateHandler)
+			notes.PUT("/:id/flags", s.putNoteFlagsHandler)
+			notes.PUT("/:id/position", s.putNotePositionHandler)
+			notes.GET("/:id/backlinks", s.backlinksHandler)
+			notes.GET("/:id/render", s.renderNoteHandler)
+			notes.GET("/:id/attachments", s.getAllAttachmentHandler)
+			notes.POST("/:id/attachments", s.postAttachmentHandler)
+			notes.GET("/:id/revisions", s.getNoteRevisionsHandler)
+			notes.GET("/:id/revisions/diff", s.diffNoteRevisionsHandler)
+			notes.GET("/:id/revisions/:number", s.getNoteRevisionHandler)
+			notes.POST("/:id/revisions/:number/restore", s.restoreNoteRevisionHandler)
+		}
+
+		tags := app.Group("/tags")
+		{
+			tags.GET("", s.getAllTagHandler)
+			tags.PUT("", s.putTagHandler)
+			tags.DELETE("", s.deleteTagHandler)
+			tags.POST("/merge", s.mergeTagHandler)
+			tags.GET("/tree", s.getTagTreeHandler)
+			tags.GET("/search", s.searchByTagsHandler)
+			tags.GET("/suggest", s.suggestTagHandler)
+			tags.POST("/suggest", s.suggestTagForContentHandler)
+			tags.GET("/aliases", s.getAllTagAliasHandler)
+			tags.PUT("/aliases", s.putTagAliasHandler)
+			tags.DELETE("/aliases", s.deleteTagAliasHandler)
+			tags.GET("/graph", s.tagGraphHandler)
+			tags.GET("/:name/related", s.relatedTagHandler)
+		}
+
+		tagRules := app.Group("/rules")
+		{
+			tagRules.GET("", s.getAllRuleHandler)
+			tagRules.POST("", s.postRuleHandler)
+			tagRules.PUT("", s.putRuleHandler)
+			tagRules.DELETE("", s.deleteRuleHandler)
+			tagRules.POST("/apply", s.applyRulesHandler)
+			tagRules.GET("/apply", s.applyRulesStatusHandler)
+		}
+
+		collections := app.Group("/collections")
+		{
+			collections.GET("", s.getAllCollectionHandler)
+			collections.POST("", s.postCollectionHandler)
+			collections.POST("/items", s.moveToCollectionHandler)
+			collections.PUT("/:id", s.putCollectionHandler)
+			collections.POST("/:id/move", s.moveCollectionHandler)
+			collections.DELETE("/:id", s.deleteCollectionHandler)
+		}
+
+		imports := app.Group("/import")
+		{
+			imports.POST("/:format", s.importHandler)
+		}
+
+		exports := app.Group("/export")
+		{
+			exports.GET("/netscape", s.exportNetscapeHandler)
+		}
+
+		journal := app.Group("/journal")
+		{
+			journal.GET("/calendar", s.journalCalendarHandler)
+			journal.GET("/streak", s.journalStreakHandler)
+			journal.GET("/:date", s.journalHandler)
+		}
+
+		noteTemplates := app.Group("/templates")
+		{
+			noteTemplates.GET("", s.getAllTemplateHandler)
+			noteTemplates.POST("", s.postTemplateHandler)
+			noteTemplates.GET("/:id", s.getTemplateHandler)
+			noteTemplates.PUT("/:id", s.putTemplateHandler)
+			noteTemplates.DELETE("/:id", s.deleteTemplateHandler)
+		}
+
+		attachments := app.Group("/attachments")
+		{
+			attachments.GET("/usage", s.attachmentUsageHandler)
+			attachments.GET("/:id", s.getAttachmentHandler)
+			attachments.DELETE("/:id", s.deleteAttachmentHandler)
+		}
+
+		app.GET("/graph", s.knowledgeGraphHandler)
+		app.POST("/render", s.previewHandler)
+
+		settings := app.Group("/settings")
+		{
+			settings.GET("", s.getSettingsHandler)
+			settings.PUT("", s.putSettingsHandler)
+		}
+
+		trash := app.Group("/trash")
+		{
+			trash.GET("", s.getTrashHandler)
+			trash.DELETE("", s.emptyTrashHandler)
+			trash.POST("/notes/:id/restore", s.restoreNoteHandler)
+			trash.DELETE("/notes/:id", s.purgeNoteHandler)
+			trash.POST("/bookmarks/:id/restore", s.restoreBookmarkHandler)
+			trash.DELETE("/bookmarks/:id", s.purgeBookmarkHandler)
 		}
 	}
 }
\ No newline at end of file
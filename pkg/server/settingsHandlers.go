@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/box1bs/TelegraphicVault/pkg/database"
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func settingsResponse(user *model.User) gin.H {
+	return gin.H{
+		"revision_limit": user.RevisionLimit,
+		"revision_days": user.RevisionDays,
+		"timezone": user.Timezone,
+		"journal_template_id": user.JournalTemplateID,
+	}
+}
+
+func (s *server) getSettingsHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	user, err := s.store.FindByID(id)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(200, settingsResponse(user))
+}
+
+func (s *server) putSettingsHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	var payload storage.UserSettings
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	user, err := s.store.UpdateUserSettings(context.Background(), id, payload)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidSettings) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(200, settingsResponse(user))
+}
@@ -0,0 +1,358 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/box1bs/TelegraphicVault/pkg/database"
+	"github.com/box1bs/TelegraphicVault/pkg/graph"
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var tagColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+const (
+	defaultSuggestionLimit 	= 10
+	maxSuggestionLimit 		= 50
+	defaultRelatedLimit 	= 20
+	maxRelatedLimit 		= 100
+)
+
+func (s *server) getAllTagHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	tags, err := s.store.ListTags(context.Background(), storage.TagFilter{UserID: id})
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(200, tags)
+}
+
+func (s *server) getTagTreeHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	tree, err := s.store.GetTagTree(context.Background(), id)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(200, tree)
+}
+
+func (s *server) searchByTagsHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	query := parseTagQuery(c)
+	if query.IsEmpty() {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	notes, bookmarks, err := s.store.SearchByTags(context.Background(), id, query)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(200, gin.H{"notes": notes, "bookmarks": bookmarks})
+}
+
+func (s *server) suggestTagHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	tags, err := s.store.SuggestTags(context.Background(), id, c.Query("prefix"), queryLimit(c, defaultSuggestionLimit, maxSuggestionLimit))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(200, tags)
+}
+
+func (s *server) suggestTagForContentHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	var payload struct {
+		Title 		string `json:"title"`
+		Description string `json:"description"`
+		Content 	string `json:"content"`
+	}
+
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	text := strings.Join([]string{payload.Title, payload.Description, payload.Content}, "\n")
+	tags, err := s.store.SuggestTagsForContent(context.Background(), id, text, queryLimit(c, defaultSuggestionLimit, maxSuggestionLimit))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(200, tags)
+}
+
+func (s *server) relatedTagHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	related, err := s.store.GetRelatedTags(context.Background(), id, c.Param("name"), queryLimit(c, defaultRelatedLimit, maxRelatedLimit))
+	if err != nil {
+		writeTagError(c, err)
+		return
+	}
+
+	c.JSON(200, related)
+}
+
+func (s *server) tagGraphHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	tags, err := s.store.ListTags(context.Background(), storage.TagFilter{UserID: id})
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	pairs, err := s.store.GetTagCooccurrences(context.Background(), id)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	g := &graph.Graph{Nodes: []graph.Node{}, Edges: []graph.Edge{}}
+	for _, tag := range tags {
+		g.Nodes = append(g.Nodes, graph.Node{ID: tag.ID.String(), Label: tag.Name, Kind: "tag", Weight: tag.Count})
+	}
+	for _, pair := range pairs {
+		g.Edges = append(g.Edges, graph.Edge{Source: pair.Source.String(), Target: pair.Target.String(), Kind: "cooccurrence", Weight: pair.Weight})
+	}
+
+	writeGraph(c, g, "tags")
+}
+
+func (s *server) putTagHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	var payload struct {
+		CurrentName 	string 	`json:"current_name"`
+		NewName 		string 	`json:"new_name"`
+		Color 			*string `json:"color"`
+		Description 	*string `json:"description"`
+	}
+
+	if err := c.ShouldBindJSON(&payload); err != nil || payload.CurrentName == "" {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if payload.Color != nil && *payload.Color != "" && !tagColorPattern.MatchString(*payload.Color) {
+		c.JSON(400, gin.H{"error": "invalid color"})
+		return
+	}
+
+	tag, err := s.store.UpdateTag(context.Background(), id, payload.CurrentName, payload.NewName, payload.Color, payload.Description)
+	if err != nil {
+		writeTagError(c, err)
+		return
+	}
+
+	c.JSON(200, tag)
+}
+
+func (s *server) mergeTagHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	var payload struct {
+		Source 	string `json:"source"`
+		Target 	string `json:"target"`
+	}
+
+	if err := c.ShouldBindJSON(&payload); err != nil || payload.Source == "" || payload.Target == "" {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	tag, err := s.store.MergeTags(context.Background(), id, payload.Source, payload.Target)
+	if err != nil {
+		writeTagError(c, err)
+		return
+	}
+
+	c.JSON(200, tag)
+}
+
+func (s *server) deleteTagHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	name := c.Query("name")
+	if name == "" {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := s.store.DeleteTag(context.Background(), id, name); err != nil {
+		writeTagError(c, err)
+		return
+	}
+
+	c.JSON(204, nil)
+}
+
+func (s *server) getAllTagAliasHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	sets, err := s.store.ListTagAliases(context.Background(), id)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(200, sets)
+}
+
+func (s *server) putTagAliasHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	var payload struct {
+		Tag 	string 		`json:"tag"`
+		Aliases []string 	`json:"aliases"`
+	}
+
+	if err := c.ShouldBindJSON(&payload); err != nil || payload.Tag == "" {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	set, err := s.store.SetTagAliases(context.Background(), id, payload.Tag, payload.Aliases)
+	if err != nil {
+		writeTagError(c, err)
+		return
+	}
+
+	c.JSON(200, set)
+}
+
+func (s *server) deleteTagAliasHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	alias := c.Query("alias")
+	if alias == "" {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := s.store.DeleteTagAlias(context.Background(), id, alias); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": "alias not found"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(204, nil)
+}
+
+func writeTagError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(404, gin.H{"error": "tag not found"})
+	case errors.Is(err, model.ErrAlreadyExists):
+		c.JSON(409, gin.H{"error": "tag already exists"})
+	case errors.Is(err, model.ErrInvalidTag):
+		c.JSON(400, gin.H{"error": "invalid tag"})
+	default:
+		c.JSON(500, gin.H{"error": "internal error"})
+	}
+}
+
+// parseTagQuery reads tags_all, tags_any and tags_none; each accepts repeated parameters and comma-separated lists.
+func parseTagQuery(c *gin.Context) storage.TagQuery {
+	list := func(key string) []string {
+		var names []string
+		for _, value := range c.QueryArray(key) {
+			for _, name := range strings.Split(value, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					names = append(names, name)
+				}
+			}
+		}
+		return names
+	}
+
+	return storage.TagQuery{
+		All: list("tags_all"),
+		Any: list("tags_any"),
+		None: list("tags_none"),
+	}
+}
+
+func queryLimit(c *gin.Context, defaultLimit, maxLimit int) int {
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		return defaultLimit
+	}
+	return min(limit, maxLimit)
+}
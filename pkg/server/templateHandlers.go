@@ -0,0 +1,260 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+	"github.com/box1bs/TelegraphicVault/pkg/templates"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type templatePayload struct {
+	Name 	string 					`json:"name"`
+	Title 	string 					`json:"title"`
+	Content string 					`json:"content"`
+	Tags 	[]string 				`json:"tags"`
+	Prompts []model.TemplatePrompt 	`json:"prompts"`
+}
+
+func (p templatePayload) toTemplate(userID uuid.UUID) *model.NoteTemplate {
+	tags := p.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	prompts := p.Prompts
+	if prompts == nil {
+		prompts = []model.TemplatePrompt{}
+	}
+
+	return &model.NoteTemplate{
+		Name: p.Name,
+		Title: p.Title,
+		Content: p.Content,
+		Tags: tags,
+		Prompts: prompts,
+		UserID: userID,
+	}
+}
+
+func (s *server) getAllTemplateHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	noteTemplates, err := s.store.ListTemplates(context.Background(), id)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(200, noteTemplates)
+}
+
+func (s *server) getTemplateHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	templateID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	template, err := s.store.GetTemplate(context.Background(), id, templateID)
+	if err != nil {
+		writeTemplateError(c, err)
+		return
+	}
+
+	c.JSON(200, template)
+}
+
+func (s *server) postTemplateHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	var payload templatePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	template := payload.toTemplate(id)
+	template.ID = uuid.New()
+	if err := s.store.CreateTemplate(context.Background(), template); err != nil {
+		writeTemplateError(c, err)
+		return
+	}
+
+	c.JSON(201, template)
+}
+
+func (s *server) putTemplateHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	templateID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	var payload templatePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	template := payload.toTemplate(id)
+	template.ID = templateID
+	updated, err := s.store.UpdateTemplate(context.Background(), template)
+	if err != nil {
+		writeTemplateError(c, err)
+		return
+	}
+
+	c.JSON(200, updated)
+}
+
+func (s *server) deleteTemplateHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	templateID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := s.store.DeleteTemplate(context.Background(), id, templateID); err != nil {
+		writeTemplateError(c, err)
+		return
+	}
+
+	c.JSON(204, nil)
+}
+
+// noteFromTemplateHandler creates a note from a template: the placeholders are filled in from the
+// current time, the requested title and the prompt values, then the template tags and the extra
+// tags of the request are added.
+func (s *server) noteFromTemplateHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	templateID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	var payload struct {
+		Title 	string 				`json:"title"`
+		Values 	map[string]string 	`json:"values"`
+		Tags 	[]string 			`json:"tags"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	for _, tag := range payload.Tags {
+		if strings.TrimSpace(tag) == "" {
+			writeTemplateError(c, model.ErrInvalidTag)
+			return
+		}
+	}
+
+	template, err := s.store.GetTemplate(context.Background(), id, templateID)
+	if err != nil {
+		writeTemplateError(c, err)
+		return
+	}
+
+	user, err := s.store.FindByID(id)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	note, err := s.createNoteFromTemplate(model.Note{UserID: id, Title: payload.Title}, template, time.Now().In(userLocation(user)), payload.Values, payload.Tags)
+	if err != nil {
+		writeTemplateError(c, err)
+		return
+	}
+
+	c.JSON(201, note)
+}
+
+// createNoteFromTemplate fills in the title and content of note, which carries the owner and the
+// requested title, from a template. It goes through CreateNote like a note created by hand, so tag
+// rules, links and revisions apply as usual. The tags are attached in the same transaction, so a
+// failure leaves no note behind. A journal note whose title is taken gets a numbered title instead.
+func (s *server) createNoteFromTemplate(note model.Note, template *model.NoteTemplate, now time.Time, values map[string]string, extraTags []string) (*model.Note, error) {
+	title, content, err := templates.Instantiate(template, now, note.Title, values)
+	if err != nil {
+		return nil, err
+	}
+
+	note.Content = content
+	tags := append(append([]string{}, template.Tags...), extraTags...)
+	for attempt := 1; ; attempt++ {
+		note.ID = uuid.New()
+		note.Title = title
+		if attempt > 1 {
+			note.Title = fmt.Sprintf("%s (%d)", title, attempt)
+		}
+
+		err := s.store.CreateNote(context.Background(), note, tags)
+		if err == nil {
+			break
+		}
+		// a journal note has to be created even when an ordinary note already has its title
+		if !errors.Is(err, model.ErrAlreadyExists) || note.Type != model.NoteTypeJournal || attempt == journalTitleAttempts {
+			return nil, err
+		}
+	}
+
+	return s.store.GetNoteByID(context.Background(), note.UserID, note.ID)
+}
+
+func writeTemplateError(c *gin.Context, err error) {
+	var missing *templates.MissingValuesError
+	switch {
+	case errors.As(err, &missing):
+		c.JSON(400, gin.H{"error": "missing template values", "missing": missing.Names})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(404, gin.H{"error": "template not found"})
+	case errors.Is(err, model.ErrInvalidTemplate):
+		c.JSON(400, gin.H{"error": "invalid template"})
+	case errors.Is(err, model.ErrInvalidTag):
+		c.JSON(400, gin.H{"error": "invalid tag"})
+	case errors.Is(err, model.ErrAlreadyExists):
+		c.JSON(409, gin.H{"error": err.Error()})
+	default:
+		c.JSON(500, gin.H{"error": "internal error"})
+	}
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// runTrashPurger permanently deletes items that stayed in the trash longer than the retention period.
+func (s *server) runTrashPurger() {
+	if s.trash.PurgeInterval <= 0 || s.trash.Retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.trash.PurgeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := s.store.PurgeTrash(context.Background(), time.Now().Add(-s.trash.Retention)); err != nil {
+			log.Printf("purging trash failed: %v\n", err)
+		}
+	}
+}
+
+func (s *server) getTrashHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	trash, err := s.store.ListTrash(context.Background(), id)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(200, trash)
+}
+
+func (s *server) emptyTrashHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	if err := s.store.EmptyTrash(context.Background(), id); err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(204, nil)
+}
+
+func (s *server) restoreNoteHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	noteID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	note, err := s.store.RestoreNote(context.Background(), id, noteID)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrAlreadyExists):
+			c.JSON(409, gin.H{"error": "note with this title already exists"})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(404, gin.H{"error": "note not found in trash"})
+		default:
+			c.JSON(500, gin.H{"error": "internal error"})
+		}
+		return
+	}
+
+	c.JSON(200, note)
+}
+
+func (s *server) restoreBookmarkHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	bookmarkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	bookmark, err := s.store.RestoreBookmark(context.Background(), id, bookmarkID)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrAlreadyExists):
+			c.JSON(409, gin.H{"error": "bookmark with this url already exists"})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(404, gin.H{"error": "bookmark not found in trash"})
+		default:
+			c.JSON(500, gin.H{"error": "internal error"})
+		}
+		return
+	}
+
+	c.JSON(200, bookmark)
+}
+
+func (s *server) purgeNoteHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	noteID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := s.store.PurgeNote(context.Background(), id, noteID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": "note not found in trash"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(204, nil)
+}
+
+func (s *server) purgeBookmarkHandler(c *gin.Context) {
+	id, err := extractUserId(c)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	bookmarkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := s.store.PurgeBookmark(context.Background(), id, bookmarkID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(404, gin.H{"error": "bookmark not found in trash"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(204, nil)
+}
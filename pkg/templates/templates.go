@@ -0,0 +1,125 @@
+package templates
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/box1bs/TelegraphicVault/pkg/model"
+)
+
+// built-in placeholders, filled in from the time of creation and the requested title
+const (
+	PlaceholderDate     = "date"
+	PlaceholderTime     = "time"
+	PlaceholderDateTime = "datetime"
+	PlaceholderWeekday  = "weekday"
+	PlaceholderTitle    = "title"
+)
+
+var builtins = []string{PlaceholderDate, PlaceholderTime, PlaceholderDateTime, PlaceholderWeekday, PlaceholderTitle}
+
+var (
+	placeholder = regexp.MustCompile(`\{\{\s*([\p{L}_][\p{L}\p{N}_-]*)\s*\}\}`)
+	promptName  = regexp.MustCompile(`^[\p{L}_][\p{L}\p{N}_-]*$`)
+)
+
+// MissingValuesError lists the required prompts that got no value.
+type MissingValuesError struct {
+	Names []string
+}
+
+func (e *MissingValuesError) Error() string {
+	return "missing template values: " + strings.Join(e.Names, ", ")
+}
+
+// Placeholders returns the distinct placeholder names used in s in order of appearance.
+func Placeholders(s string) []string {
+	var names []string
+	for _, match := range placeholder.FindAllStringSubmatch(s, -1) {
+		if !slices.Contains(names, match[1]) {
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// Validate checks that a template has a name, that its prompts have distinct names that do not
+// shadow the built-in placeholders and that every placeholder it uses is known.
+func Validate(t *model.NoteTemplate) error {
+	if strings.TrimSpace(t.Name) == "" {
+		return model.ErrInvalidTemplate
+	}
+
+	known := slices.Clone(builtins)
+	for _, prompt := range t.Prompts {
+		if !promptName.MatchString(prompt.Name) || slices.Contains(known, prompt.Name) {
+			return model.ErrInvalidTemplate
+		}
+		known = append(known, prompt.Name)
+	}
+
+	for _, name := range Placeholders(t.Title + "\n" + t.Content) {
+		if !slices.Contains(known, name) {
+			return model.ErrInvalidTemplate
+		}
+	}
+
+	for _, tag := range t.Tags {
+		if strings.TrimSpace(tag) == "" {
+			return model.ErrInvalidTemplate
+		}
+	}
+
+	return nil
+}
+
+// Instantiate returns the title and content of a note created from t at now. values fill the
+// prompts, falling back to their defaults. An empty template title means "{{title}}".
+func Instantiate(t *model.NoteTemplate, now time.Time, title string, values map[string]string) (string, string, error) {
+	filled := map[string]string{
+		PlaceholderDate:     now.Format(time.DateOnly),
+		PlaceholderTime:     now.Format("15:04"),
+		PlaceholderDateTime: now.Format("2006-01-02 15:04"),
+		PlaceholderWeekday:  now.Weekday().String(),
+		PlaceholderTitle:    strings.TrimSpace(title),
+	}
+
+	var missing []string
+	for _, prompt := range t.Prompts {
+		value := strings.TrimSpace(values[prompt.Name])
+		if value == "" {
+			value = prompt.Default
+		}
+		if value == "" && prompt.Required {
+			missing = append(missing, prompt.Name)
+		}
+		filled[prompt.Name] = value
+	}
+
+	titlePattern := t.Title
+	if strings.TrimSpace(titlePattern) == "" {
+		titlePattern = "{{" + PlaceholderTitle + "}}"
+	}
+
+	noteTitle := strings.TrimSpace(expand(titlePattern, filled))
+	if noteTitle == "" && len(missing) == 0 {
+		missing = append(missing, PlaceholderTitle)
+	}
+	if len(missing) > 0 {
+		return "", "", &MissingValuesError{Names: missing}
+	}
+
+	return noteTitle, expand(t.Content, filled), nil
+}
+
+func expand(s string, values map[string]string) string {
+	return placeholder.ReplaceAllStringFunc(s, func(match string) string {
+		value, ok := values[placeholder.FindStringSubmatch(match)[1]]
+		if !ok {
+			return match
+		}
+		return value
+	})
+}
@@ -0,0 +1,55 @@
+package urlnorm
+
+import (
+	"net/url"
+	"strings"
+)
+
+// query parameters that only identify where a visitor came from
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "dclid": true, "gbraid": true, "wbraid": true,
+	"msclkid": true, "yclid": true, "igshid": true, "mc_cid": true, "mc_eid": true,
+	"_ga": true, "_gl": true, "_hsenc": true, "_hsmi": true, "ref_src": true, "spm": true,
+}
+
+// Canonical returns the form of rawURL used to detect duplicate bookmarks: the scheme is
+// https, the host is lowercased without "www." and default ports, tracking parameters and
+// the fragment are dropped, the remaining parameters are sorted by name, keeping the order
+// of repeated values, and the trailing slash of the path is removed. Strings that do not parse as absolute URLs are returned trimmed.
+func Canonical(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if scheme == "http" {
+		scheme = "https"
+	}
+
+	host := strings.ToLower(u.Hostname())
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "www."), ".")
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	query := u.Query()
+	for key := range query {
+		if trackingParams[strings.ToLower(key)] || strings.HasPrefix(strings.ToLower(key), "utm_") {
+			query.Del(key)
+		}
+	}
+
+	// Encode sorts by key; the values of a repeated key keep their order, which can matter
+	canonical := &url.URL{
+		Scheme:   scheme,
+		Host:     host,
+		Path:     strings.TrimRight(u.Path, "/"),
+		RawQuery: query.Encode(),
+	}
+	return canonical.String()
+}
@@ -0,0 +1,56 @@
+package urlnorm
+
+import "testing"
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"http becomes https", "http://example.com/page", "https://example.com/page"},
+		{"scheme case", "HTTPS://example.com/page", "https://example.com/page"},
+		{"host case", "https://Example.COM/Page", "https://example.com/Page"},
+		{"www prefix", "https://www.example.com/", "https://example.com"},
+		{"trailing dot of the host", "https://example.com./a", "https://example.com/a"},
+		{"default http port", "http://example.com:80/a", "https://example.com/a"},
+		{"default https port", "https://example.com:443/a", "https://example.com/a"},
+		{"other port", "https://example.com:8080/a", "https://example.com:8080/a"},
+		{"ipv6 host", "http://[::1]:8080/a", "https://[::1]:8080/a"},
+		{"trailing slash", "https://example.com/a/b/", "https://example.com/a/b"},
+		{"root path", "https://example.com/", "https://example.com"},
+		{"fragment", "https://example.com/a#section", "https://example.com/a"},
+		{"utm parameters", "https://example.com/a?utm_source=x&UTM_MEDIUM=y&id=1", "https://example.com/a?id=1"},
+		{"click ids", "https://example.com/a?fbclid=1&gclid=2&q=go", "https://example.com/a?q=go"},
+		{"only tracking parameters", "https://example.com/a?utm_campaign=x", "https://example.com/a"},
+		{"parameters sorted by key", "https://example.com/a?b=2&a=1&c=3", "https://example.com/a?a=1&b=2&c=3"},
+		{"repeated keys keep their order", "https://example.com/a?tag=z&page=1&tag=a", "https://example.com/a?page=1&tag=z&tag=a"},
+		{"escaping", "https://example.com/a?q=a+b&r=%2F", "https://example.com/a?q=a+b&r=%2F"},
+		{"surrounding space", "  https://example.com/a  ", "https://example.com/a"},
+		{"relative url", "example.com/a", "example.com/a"},
+		{"not a url", " ::not a url ", "::not a url"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Canonical(tt.in); got != tt.want {
+				t.Errorf("Canonical(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalMatchesDuplicates(t *testing.T) {
+	urls := []string{
+		"http://www.example.com/post/?utm_source=feed#comments",
+		"https://example.com/post",
+		"HTTPS://EXAMPLE.com:443/post/",
+	}
+
+	want := Canonical(urls[0])
+	for _, u := range urls[1:] {
+		if got := Canonical(u); got != want {
+			t.Errorf("Canonical(%q) = %q, want %q", u, got, want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package wikilink
+
+import (
+	"regexp"
+	"strings"
+)
+
+// link matches [[Title]], [[Title#Heading]] and [[Title|label]] with optional heading and label.
+var link = regexp.MustCompile(`\[\[([^\[\]|#\n]+)(#[^\[\]|\n]*)?(\|[^\[\]\n]*)?\]\]`)
+
+// Link is a single [[...]] occurrence.
+type Link struct {
+	Title   string
+	Heading string
+	Label   string
+}
+
+// Find returns every link in content in order of appearance.
+func Find(content string) []Link {
+	var links []Link
+	for _, m := range link.FindAllStringSubmatch(content, -1) {
+		title := strings.TrimSpace(m[1])
+		if title == "" {
+			continue
+		}
+		links = append(links, Link{
+			Title: title,
+			Heading: strings.TrimSpace(strings.TrimPrefix(m[2], "#")),
+			Label: strings.TrimSpace(strings.TrimPrefix(m[3], "|")),
+		})
+	}
+	return links
+}
+
+// Titles returns the distinct note titles linked from content in order of first appearance.
+func Titles(content string) []string {
+	seen := make(map[string]bool)
+	var titles []string
+	for _, l := range Find(content) {
+		if !seen[l.Title] {
+			seen[l.Title] = true
+			titles = append(titles, l.Title)
+		}
+	}
+	return titles
+}
+
+// Linkable reports whether title can be the target of a link: the brackets, "#" and "|" would
+// end the title early or make it a heading or a label.
+func Linkable(title string) bool {
+	return strings.TrimSpace(title) != "" && !strings.ContainsAny(title, "[]|#\n")
+}
+
+// Rewrite points the links to oldTitle at newTitle, keeping their headings and labels.
+func Rewrite(content, oldTitle, newTitle string) string {
+	return link.ReplaceAllStringFunc(content, func(match string) string {
+		m := link.FindStringSubmatch(match)
+		if strings.TrimSpace(m[1]) != oldTitle {
+			return match
+		}
+		return "[[" + newTitle + m[2] + m[3] + "]]"
+	})
+}